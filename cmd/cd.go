@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/selector"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var cdCmd = &cobra.Command{
+	Use:   "cd [branch]",
+	Short: "Print the path of a worktree",
+	Long: `Print the absolute path of a worktree and nothing else, for use in shell
+expressions:
+
+  cd "$(wk cd feature-x)"
+  alias wcd='cd "$(wk cd)"'
+
+If branch is not specified, opens an interactive selector on stderr.
+Cancelling the selector exits with a non-zero status and prints nothing.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	Annotations:  map[string]string{skipUpdateCheck: "true"},
+	RunE:         runCd,
+}
+
+func init() {
+	rootCmd.AddCommand(cdCmd)
+}
+
+func runCd(cmd *cobra.Command, args []string) error {
+	var branch string
+	if len(args) == 1 {
+		branch = args[0]
+	} else {
+		selected, err := selector.SelectWorktreeTo(os.Stderr)
+		if err != nil {
+			return err
+		}
+		branch = selected
+	}
+
+	wt, err := worktree.FindByBranch(branch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(wt.Path)
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+// completeNewBranches suggests branches, local and remote-only, that don't
+// have a worktree yet.
+func completeNewBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	applyConfig()
+
+	branches, err := worktree.ListBranches()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	existing, err := worktree.ListWorktreeBranches()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, b := range branches {
+		if !existing[b.Name] {
+			names = append(names, b.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRefs suggests local branches, remote branches and tags.
+func completeRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	refs, err := worktree.ListRefs()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return refs, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeMoveArgs suggests branches that have a worktree, then directories
+// for the destination.
+func completeMoveArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 1 {
+		return nil, cobra.ShellCompDirectiveFilterDirs
+	}
+	return completeWorktreeBranches(cmd, args, toComplete)
+}
+
+// completeWorktreeBranches suggests branches that have a worktree.
+func completeWorktreeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	worktrees, err := worktree.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, wt := range worktrees {
+		if wt.Branch != "" && wt.Branch != "(detached)" {
+			names = append(names, wt.Branch)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
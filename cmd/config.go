@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the configuration in effect",
+	Long: `Inspect the configuration wk uses in this repository.
+
+'wk config show' prints the global configuration merged with .wk.yaml, as
+'wk new' sees it, with the file each value came from as a comment.
+'wk config path' prints the path of the .wk.yaml in use. Both follow --config.
+
+'wk config worktrees-dir' prints the directory wk creates worktrees in, e.g.
+to add it to .gitignore or point other tools at it. It creates nothing.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the merged configuration with the source of each value",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigShow,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the configuration file in use",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigPath,
+}
+
+var configWorktreesDirCmd = &cobra.Command{
+	Use:   "worktrees-dir",
+	Short: "Print the directory new worktrees are created in",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigWorktreesDir,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configWorktreesDirCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	res, err := config.Resolve(wd)
+	if os.IsNotExist(err) {
+		return exit.WithCode(exit.NotFound, fmt.Errorf("no configuration found; run 'wk init' to create %s", config.ConfigFileName))
+	}
+	if err != nil {
+		return exit.WithCode(exit.InvalidConfig, err)
+	}
+
+	var node yaml.Node
+	if err := node.Encode(res.Config); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	annotateOrigins(&node, res)
+
+	for _, f := range res.Files {
+		fmt.Printf("# loaded %s\n", f)
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	return enc.Close()
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	path, err := config.FindConfig(wd)
+	if os.IsNotExist(err) {
+		return exit.WithCode(exit.NotFound, fmt.Errorf("no %s found; run 'wk init' to create one", config.ConfigFileName))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
+
+func runConfigWorktreesDir(cmd *cobra.Command, args []string) error {
+	dir, err := worktree.GetWorktreesDir()
+	if err != nil {
+		return err
+	}
+	fmt.Println(dir)
+	return nil
+}
+
+// annotateOrigins adds a "from <file>" comment to each value of node, the
+// encoded configuration, using the origins recorded in res.
+func annotateOrigins(node *yaml.Node, res *config.Resolution) {
+	entries := configEntries(res.Config)
+
+	fields := node.Content
+	for i := 0; i+1 < len(fields); i += 2 {
+		field, value := fields[i].Value, fields[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			setOrigin(value, res.Origin(field, value.Value))
+		case yaml.SequenceNode:
+			for j, item := range value.Content {
+				if j < len(entries[field]) {
+					setOrigin(item, res.Origin(field, entries[field][j]))
+				}
+			}
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				setOrigin(value.Content[j+1], res.Origin(field, value.Content[j].Value))
+			}
+		}
+	}
+}
+
+// configEntries returns the entries of cfg's list fields as recorded by
+// config.Resolution, in YAML order.
+func configEntries(cfg *config.Config) map[string][]string {
+	runs := func(list []config.Hook) []string {
+		result := make([]string, len(list))
+		for i, h := range list {
+			result[i] = h.Run
+		}
+		return result
+	}
+	copies := make([]string, len(cfg.Copy))
+	for i, e := range cfg.Copy {
+		copies[i] = e.String()
+	}
+	return map[string][]string{
+		"copy":             copies,
+		"link":             cfg.Link,
+		"post_hooks":       runs(cfg.PostHooks),
+		"post_create_main": runs(cfg.PostCreateMain),
+		"verify":           runs(cfg.Verify),
+		"on_remove":        cfg.OnRemove,
+	}
+}
+
+// setOrigin notes origin as a comment on node, if known. For a hook written
+// as an object, the comment goes on its run command.
+func setOrigin(node *yaml.Node, origin string) {
+	if origin == "" {
+		return
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "run" {
+				node = node.Content[i+1]
+				break
+			}
+		}
+	}
+	node.LineComment = "from " + origin
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var diffWorkingTree bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <branchA> <branchB> [-- git-diff-options...]",
+	Short: "Compare two worktrees",
+	Long: `Show the differences between the worktrees of two branches.
+
+By default compares the HEAD commits of both worktrees. Use -w/--working-tree
+to include uncommitted changes to tracked files in both worktrees.
+
+Extra options after -- are passed to git diff, e.g.:
+  wk diff main feature-x -- --stat`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVarP(&diffWorkingTree, "working-tree", "w", false, "Include uncommitted changes of both worktrees")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	from, err := diffRef(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := diffRef(args[1])
+	if err != nil {
+		return err
+	}
+
+	gitArgs := append([]string{"diff"}, args[2:]...)
+	gitArgs = append(gitArgs, from, to)
+
+	gitCmd := exec.Command(worktree.GitBin(), gitArgs...)
+	gitCmd.Stdin = os.Stdin
+	gitCmd.Stdout = os.Stdout
+	gitCmd.Stderr = os.Stderr
+
+	return gitCmd.Run()
+}
+
+// diffRef resolves the commit to compare for the worktree of branch.
+func diffRef(branch string) (string, error) {
+	wt, err := worktree.FindByBranch(branch)
+	if err != nil {
+		return "", err
+	}
+
+	if !diffWorkingTree {
+		return wt.Commit, nil
+	}
+
+	commit, err := worktree.SnapshotCommit(wt.Path)
+	if err != nil {
+		return "", fmt.Errorf("snapshot worktree '%s': %w", branch, err)
+	}
+	return commit, nil
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/state"
+	"github.com/lucas-stellet/wk/internal/updater"
+	"github.com/lucas-stellet/wk/internal/validate"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check wk's environment and the repository's worktrees for problems",
+	Long: `Run diagnostic checks and print a pass/fail report.
+
+Checks:
+  - git is installed (or WK_GIT_BIN is executable) and recent enough
+  - the current directory is inside a git repository
+  - .wk.yaml, if present, is valid
+  - the worktrees directory can be written to
+  - the update-check cache in the cache directory can be read and written
+  - every linked worktree's .git file points back to this repository's
+    admin directory, so it shares the main object store
+
+Exits with a non-zero status if any check fails. Warnings, such as a missing
+.wk.yaml, don't count as failures.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	// Runs its own repository checks, so it also works outside a repository
+	Annotations: map[string]string{validate.SkipGitValidation: "true"},
+	RunE:        runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// minGitVersion is the oldest git with 'git worktree move' and 'remove'.
+var minGitVersion = [2]int{2, 17}
+
+// doctorReport prints check results and counts failures.
+type doctorReport struct {
+	failed int
+}
+
+func (r *doctorReport) section(name string) {
+	fmt.Printf("%s:\n", name)
+}
+
+func (r *doctorReport) ok(format string, a ...any) {
+	fmt.Printf("  ok    "+format+"\n", a...)
+}
+
+func (r *doctorReport) warn(format string, a ...any) {
+	fmt.Printf("  warn  "+format+"\n", a...)
+}
+
+func (r *doctorReport) fail(format string, a ...any) {
+	fmt.Printf("  FAIL  "+format+"\n", a...)
+	r.failed++
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	r := &doctorReport{}
+
+	r.section("Git")
+	gitOK := checkGit(r)
+
+	fmt.Println()
+	r.section("Repository")
+	inRepo := false
+	if !gitOK {
+		r.warn("skipped: git is not available")
+	} else if err := validate.IsGitRepository(); err != nil {
+		r.fail("not inside a git repository")
+	} else {
+		inRepo = true
+		r.ok("inside a git repository")
+	}
+
+	fmt.Println()
+	r.section("Configuration")
+	checkConfigFile(r)
+
+	if inRepo {
+		fmt.Println()
+		r.section("Worktrees directory")
+		checkWorktreesDir(r)
+
+		fmt.Println()
+		r.section("Worktree links")
+		checkWorktreeLinks(r)
+	}
+
+	fmt.Println()
+	r.section("Update cache")
+	checkUpdateCache(r)
+
+	if r.failed > 0 {
+		return fmt.Errorf("%d check(s) failed", r.failed)
+	}
+	return nil
+}
+
+// checkGit reports whether git can be run and is at least minGitVersion.
+func checkGit(r *doctorReport) bool {
+	bin := worktree.GitBin()
+	path, err := exec.LookPath(bin)
+	if err != nil {
+		r.fail("git not found (%s); install git or set %s", bin, worktree.GitBinEnv)
+		return false
+	}
+
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		r.fail("%s --version failed: %v", path, err)
+		return false
+	}
+	version := strings.TrimSpace(string(out))
+
+	major, minor, ok := parseGitVersion(version)
+	switch {
+	case !ok:
+		r.warn("%s (%s): cannot determine version", version, path)
+	case major < minGitVersion[0] || (major == minGitVersion[0] && minor < minGitVersion[1]):
+		r.fail("%s (%s): wk needs git %d.%d or newer", version, path, minGitVersion[0], minGitVersion[1])
+	default:
+		r.ok("%s (%s)", version, path)
+	}
+	return true
+}
+
+// parseGitVersion extracts the major and minor version from the output of
+// 'git --version', e.g. "git version 2.39.2 (Apple Git-143)".
+func parseGitVersion(s string) (major, minor int, ok bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, errMajor := strconv.Atoi(parts[0])
+	minor, errMinor := strconv.Atoi(parts[1])
+	if errMajor != nil || errMinor != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+func checkConfigFile(r *doctorReport) {
+	exists, valid, err := validate.CheckConfig()
+	switch {
+	case !exists && err != nil:
+		r.fail("cannot look for .wk.yaml: %v", err)
+	case !exists:
+		r.warn("no .wk.yaml found; run 'wk init' to create one")
+	case !valid:
+		r.fail(".wk.yaml is invalid: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	default:
+		r.ok(".wk.yaml is valid")
+	}
+}
+
+// checkWorktreesDir checks that new worktrees can be created in the
+// worktrees dir, or in its closest existing parent if it doesn't exist yet.
+func checkWorktreesDir(r *doctorReport) {
+	dir, err := worktree.GetWorktreesDir()
+	if err != nil {
+		r.fail("cannot determine the worktrees directory: %v", err)
+		return
+	}
+
+	target := dir
+	for {
+		if _, err := os.Stat(target); err == nil {
+			break
+		}
+		parent := filepath.Dir(target)
+		if parent == target {
+			break
+		}
+		target = parent
+	}
+
+	if err := checkWritable(target); err != nil {
+		r.fail("%s is not writable: %v", target, err)
+		return
+	}
+	if target != dir {
+		r.ok("%s does not exist yet; %s is writable", dir, target)
+		return
+	}
+	r.ok("%s is writable", dir)
+}
+
+func checkWorktreeLinks(r *doctorReport) {
+	worktrees, err := worktree.List()
+	if err != nil {
+		r.fail("cannot list worktrees: %v", err)
+		return
+	}
+	for i, wt := range worktrees {
+		// The first entry is the main worktree, which has a .git directory,
+		// or a bare repository, which has none
+		if i == 0 || wt.Bare {
+			continue
+		}
+		if err := worktree.VerifyLink(wt.Path); err != nil {
+			r.fail("%s: %v", wt.Path, err)
+			continue
+		}
+		r.ok("%s", wt.Path)
+	}
+	if len(worktrees) <= 1 {
+		r.ok("no linked worktrees")
+	}
+}
+
+// checkUpdateCache checks that the update-check cache can be read and the
+// cache directory written. Problems only disable update notifications, so
+// they are warnings.
+func checkUpdateCache(r *doctorReport) {
+	dir, err := state.CacheDir()
+	if err != nil {
+		r.warn("cannot determine the cache directory: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		r.warn("cannot create %s: %v", dir, err)
+		return
+	}
+	if err := checkWritable(dir); err != nil {
+		r.warn("%s is not writable: %v", dir, err)
+		return
+	}
+
+	path, _ := updater.CachePath()
+	if _, err := updater.LoadCache(); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			r.ok("%s is writable; no update check cached yet", dir)
+			return
+		}
+		r.warn("%s is unreadable: %v; run 'wk update --clear-cache'", path, err)
+		return
+	}
+	r.ok("%s is readable", path)
+}
+
+// checkWritable creates and removes a temporary file in dir.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".wk-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+// isolateGit makes git ignore the global and system configuration and
+// commit as a fixed author for the rest of the test.
+func isolateGit(t *testing.T) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "wk")
+	t.Setenv("GIT_AUTHOR_EMAIL", "wk@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "wk")
+	t.Setenv("GIT_COMMITTER_EMAIL", "wk@example.com")
+	t.Setenv(worktree.GitBinEnv, "")
+	worktree.SetRemote("")
+	t.Cleanup(func() { worktree.SetRemote("") })
+}
+
+// git runs git with args in dir and fails the test if it fails.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// tempDir returns a temporary directory with symlinks resolved, so paths
+// compare equal to those git reports.
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
 	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/prompt"
 )
 
 var initCmd = &cobra.Command{
@@ -19,29 +21,135 @@ var initCmd = &cobra.Command{
 
 This command guides you through setting up:
   - Files to copy to new worktrees
-  - Post-creation hooks to run`,
+  - Post-creation hooks to run
+
+Use --template to write a starter configuration for a common stack instead,
+without prompts. --list-templates shows the available templates.
+
+To create the configuration from a script, pass the files to copy with --copy
+and the hooks with --hook; no prompts are shown then:
+
+  wk init --copy .env,.env.local --hook "npm ci" --hook "npm run build"
+
+If .wk.yaml already exists, wk asks before overwriting it unless --force is
+given.`,
+	Args: cobra.NoArgs,
 	RunE: runInit,
 }
 
+var (
+	initTemplate      string
+	initListTemplates bool
+	initCopy          []string
+	initHooks         []string
+	initForce         bool
+)
+
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVarP(&initTemplate, "template", "t", "", "Write the built-in starter configuration with this name")
+	initCmd.Flags().BoolVar(&initListTemplates, "list-templates", false, "List the built-in starter configurations")
+	initCmd.Flags().StringSliceVar(&initCopy, "copy", nil, "File or directory to copy to new worktrees (repeatable or comma-separated)")
+	initCmd.Flags().StringArrayVar(&initHooks, "hook", nil, "Post-creation hook command (repeatable)")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing .wk.yaml without asking")
+	initCmd.MarkFlagsMutuallyExclusive("template", "list-templates")
+	initCmd.MarkFlagsMutuallyExclusive("template", "copy")
+	initCmd.MarkFlagsMutuallyExclusive("template", "hook")
+	initCmd.RegisterFlagCompletionFunc("template", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return config.TemplateNames(), cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
+	if initListTemplates {
+		return listTemplates()
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case initTemplate != "":
+		data, err = config.Template(initTemplate)
+	case cmd.Flags().Changed("copy") || cmd.Flags().Changed("hook"):
+		data, err = flagsConfig()
+	}
+	if err != nil {
+		return err
+	}
+
 	// Check if config already exists
-	if _, err := os.Stat(config.ConfigFileName); err == nil {
-		fmt.Printf("%s already exists. Overwrite? [y/N]: ", config.ConfigFileName)
-		if !confirmPrompt() {
+	if _, err := os.Stat(config.ConfigFileName); err == nil && !initForce {
+		if !prompt.Confirm(fmt.Sprintf("%s already exists. Overwrite?", config.ConfigFileName)) {
 			fmt.Println("Aborted")
 			return nil
 		}
 	}
 
+	if data == nil {
+		data, err = promptConfig()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write file
+	if err := os.WriteFile(config.ConfigFileName, data, 0644); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+
+	fmt.Printf("\nCreated %s:\n", config.ConfigFileName)
+	fmt.Println("---")
+	fmt.Print(string(data))
+	fmt.Println("---")
+
+	return nil
+}
+
+// listTemplates prints the built-in templates with the description from
+// their first comment line.
+func listTemplates() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range config.TemplateNames() {
+		data, err := config.Template(name)
+		if err != nil {
+			return err
+		}
+		first, _, _ := strings.Cut(string(data), "\n")
+		fmt.Fprintf(w, "%s\t%s\n", name, strings.TrimPrefix(first, "# "))
+	}
+	return w.Flush()
+}
+
+// flagsConfig returns the configuration given by --copy and --hook as YAML.
+func flagsConfig() ([]byte, error) {
+	cfg := &config.Config{}
+	for _, f := range initCopy {
+		if f = strings.TrimSpace(f); f != "" {
+			cfg.Copy = append(cfg.Copy, config.CopyEntry{From: f})
+		}
+	}
+	for _, h := range initHooks {
+		if h = strings.TrimSpace(h); h != "" {
+			cfg.PostHooks = append(cfg.PostHooks, config.Hook{Run: h})
+		}
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// promptConfig asks for the files to copy and the post hooks, and returns
+// the resulting configuration as YAML.
+func promptConfig() ([]byte, error) {
 	reader := bufio.NewReader(os.Stdin)
 	cfg := &config.Config{}
 
 	fmt.Println("Creating .wk.yaml configuration")
-	fmt.Println("Press Enter to skip any section\n")
+	fmt.Println("Press Enter to skip any section")
+	fmt.Println()
 
 	// Files to copy
 	fmt.Println("Files/directories to copy to new worktrees")
@@ -50,7 +158,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 	copyInput, _ := reader.ReadString('\n')
 	copyInput = strings.TrimSpace(copyInput)
 	if copyInput != "" {
-		cfg.Copy = parseCSV(copyInput)
+		for _, f := range parseCSV(copyInput) {
+			cfg.Copy = append(cfg.Copy, config.CopyEntry{From: f})
+		}
 	}
 
 	fmt.Println()
@@ -65,33 +175,15 @@ func runInit(cmd *cobra.Command, args []string) error {
 		if hookInput == "" {
 			break
 		}
-		cfg.PostHooks = append(cfg.PostHooks, hookInput)
+		cfg.PostHooks = append(cfg.PostHooks, config.Hook{Run: hookInput})
 	}
 
 	// Generate YAML
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
+		return nil, fmt.Errorf("marshal config: %w", err)
 	}
-
-	// Write file
-	if err := os.WriteFile(config.ConfigFileName, data, 0644); err != nil {
-		return fmt.Errorf("write config: %w", err)
-	}
-
-	fmt.Printf("\nCreated %s:\n", config.ConfigFileName)
-	fmt.Println("---")
-	fmt.Print(string(data))
-	fmt.Println("---")
-
-	return nil
-}
-
-func confirmPrompt() bool {
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
-	return input == "y" || input == "yes"
+	return data, nil
 }
 
 func parseCSV(input string) []string {
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var installHooksCmd = &cobra.Command{
+	Use:   "install-hooks",
+	Short: "Run 'wk setup' automatically in worktrees created outside wk",
+	Long: `Install a git post-checkout hook that runs 'wk setup' whenever
+'git worktree add' creates a worktree, e.g. one created by Claude Code, so it
+gets the same file copies and hooks as one created with 'wk new'.
+
+The hook is written to the repository's hooks directory, or to core.hooksPath
+if set. An existing shell hook is kept and the wk part appended to it; running
+the command again changes nothing. Worktrees created by wk itself are not set
+up twice.`,
+	Args: cobra.NoArgs,
+	RunE: runInstallHooks,
+}
+
+func init() {
+	rootCmd.AddCommand(installHooksCmd)
+}
+
+func runInstallHooks(cmd *cobra.Command, args []string) error {
+	path, changed, err := worktree.InstallSetupHook()
+	if err != nil {
+		return fmt.Errorf("install hook: %w", err)
+	}
+
+	if !changed {
+		output.Printf("Hook already installed in %s\n", path)
+		return nil
+	}
+	output.Printf("Installed hook in %s\n", path)
+	return nil
+}
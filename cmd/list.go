@@ -1,12 +1,21 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
+	"github.com/lucas-stellet/wk/internal/output"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
@@ -14,41 +23,177 @@ var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List all worktrees",
-	RunE:    runList,
+	Long: `List all worktrees with their branch, path, commit, status (dirty if it
+has uncommitted changes) and upstream, and whether they are locked (see
+'wk lock').
+
+Use --filter to only list worktrees that are dirty (uncommitted changes),
+clean, merged or unmerged (into the default branch). The main worktree and
+detached worktrees are never merged or unmerged.
+
+Use --stale to only list linked worktrees whose last commit is older than a
+duration, such as 30d, 2w or 12h, with the age of that commit. Combined with
+--filter merged, this lists worktrees that are likely safe to remove.
+
+Worktrees are listed in git's order: the main worktree first, then in order
+of creation. Use --sort name or --sort path to sort them alphabetically, or
+--sort age to list the one with the most recent commit first.
+
+Output is colored when written to a terminal; use --no-color or set NO_COLOR
+to turn colors off. Use --json for machine-readable output.`,
+	Args: cobra.NoArgs,
+	RunE: runList,
 }
 
+var (
+	listFilter  string
+	listSort    string
+	listStale   string
+	listJSON    bool
+	listNoColor bool
+)
+
+// listFilters are the values accepted by --filter.
+var listFilters = []string{"dirty", "clean", "merged", "unmerged"}
+
+// listSorts are the values accepted by --sort.
+var listSorts = []string{"name", "path", "age"}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().StringVar(&listFilter, "filter", "", "Only list worktrees that are dirty, clean, merged or unmerged")
+	listCmd.Flags().StringVar(&listStale, "stale", "", "Only list worktrees whose last commit is older than this (e.g. 30d, 2w, 12h)")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort worktrees by name, path or age (most recent commit first)")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print the worktrees as JSON")
+	listCmd.Flags().BoolVar(&listNoColor, "no-color", false, "Don't color the output")
+	listCmd.RegisterFlagCompletionFunc("sort", cobra.FixedCompletions(listSorts, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// listEntry is a worktree as printed by 'wk list --json'.
+type listEntry struct {
+	Branch     string     `json:"branch"`
+	Path       string     `json:"path"`
+	Commit     string     `json:"commit"`
+	Upstream   string     `json:"upstream,omitempty"`
+	Dirty      bool       `json:"dirty"`
+	Bare       bool       `json:"bare,omitempty"`
+	Locked     bool       `json:"locked"`
+	LockReason string     `json:"lock_reason,omitempty"`
+	LastCommit *time.Time `json:"last_commit,omitempty"`
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	worktrees, err := worktree.List()
+	if listFilter != "" && !slices.Contains(listFilters, listFilter) {
+		return fmt.Errorf("invalid --filter %q: must be dirty, clean, merged or unmerged", listFilter)
+	}
+	if listSort != "" && !slices.Contains(listSorts, listSort) {
+		return fmt.Errorf("invalid --sort %q: must be name, path or age", listSort)
+	}
+	var staleAge time.Duration
+	if listStale != "" {
+		var err error
+		staleAge, err = parseAge(listStale)
+		if err != nil {
+			return fmt.Errorf("invalid --stale %q: use a duration like 30d, 2w or 12h", listStale)
+		}
+	}
+
+	repo := worktree.NewRepo()
+	worktrees, err := repo.List()
 	if err != nil {
 		return err
 	}
 
+	// In a bare clone the first entry is the bare repository, not main
+	mainPath, _ := repo.MainWorktreePath()
+	if listFilter != "" {
+		worktrees = filterWorktrees(worktrees, mainPath, listFilter)
+	}
+	var lastCommits map[string]time.Time
+	if listStale != "" {
+		worktrees, lastCommits = staleWorktrees(worktrees, mainPath, staleAge)
+	}
+	if listSort != "" {
+		worktrees = sortWorktrees(worktrees, listSort)
+	}
+
+	entries := make([]listEntry, 0, len(worktrees))
+	for _, wt := range worktrees {
+		e := listEntry{Branch: wt.Branch, Path: wt.Path, Commit: wt.Commit, Bare: wt.Bare, Locked: wt.Locked, LockReason: wt.LockReason}
+		if wt.Bare {
+			e.Branch = "(bare)"
+		} else {
+			e.Dirty, _ = worktree.IsDirty(wt.Path)
+			if wt.Branch != "(detached)" {
+				e.Upstream = worktree.GetUpstream(wt.Branch)
+			}
+		}
+		if date, ok := lastCommits[wt.Path]; ok {
+			e.LastCommit = &date
+		}
+		entries = append(entries, e)
+	}
+
+	if listJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
 	if len(worktrees) == 0 {
-		fmt.Println("No worktrees found")
+		var kind []string
+		if listStale != "" {
+			kind = append(kind, "stale")
+		}
+		if listFilter != "" {
+			kind = append(kind, listFilter)
+		}
+		fmt.Println(strings.Join(slices.Concat([]string{"No"}, kind, []string{"worktrees found"}), " "))
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "BRANCH\tPATH\tCOMMIT")
-	for _, wt := range worktrees {
-		commit := wt.Commit
+	header := []string{"BRANCH", "PATH", "COMMIT", "STATUS", "UPSTREAM", "LOCKED"}
+	if listStale != "" {
+		header = slices.Insert(header, 3, "AGE")
+	}
+	rows := [][]string{header}
+	for _, e := range entries {
+		commit := e.Commit
 		if len(commit) > 7 {
 			commit = commit[:7]
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\n", wt.Branch, wt.Path, commit)
+		var age []string
+		if e.LastCommit != nil {
+			age = []string{output.RelativeTime(*e.LastCommit)}
+		}
+		status := "clean"
+		switch {
+		case e.Bare:
+			status = "-"
+		case e.Dirty:
+			status = "dirty"
+		}
+		upstream := "-"
+		if e.Upstream != "" {
+			upstream = e.Upstream
+		}
+		locked := "-"
+		switch {
+		case e.LockReason != "":
+			locked = e.LockReason
+		case e.Locked:
+			locked = "yes"
+		}
+		rows = append(rows, slices.Concat([]string{e.Branch, e.Path, commit}, age, []string{status, upstream, locked}))
 	}
-	if err := w.Flush(); err != nil {
+	if err := printTable(rows, useColor()); err != nil {
 		return err
 	}
 
 	// Detect worktrees not in standard location
 	var nonStandard []worktree.Worktree
 	for _, wt := range worktrees {
-		isStandard, err := worktree.IsInStandardLocation(wt.Path)
+		isStandard, err := repo.IsInStandardLocation(wt.Path)
 		if err != nil {
 			continue
 		}
@@ -69,3 +214,173 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// useColor reports whether to color the list: not with --no-color, when
+// NO_COLOR is set or when stdout isn't a terminal.
+func useColor() bool {
+	return !listNoColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout.Fd())
+}
+
+// List styles, matching the selector.
+var (
+	listHeaderStyle = lipgloss.NewStyle().Bold(true)
+	listDimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	listCommitStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("78"))
+	listDirtyStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+	listLockStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+)
+
+// printTable prints rows, the first being the header, aligned with a
+// tabwriter. With color, cells are styled after alignment, so escape
+// sequences don't count towards column widths.
+func printTable(rows [][]string, color bool) error {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !color {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		var b strings.Builder
+		pos := 0
+		for col, cell := range rows[i] {
+			start := pos + strings.Index(line[pos:], cell)
+			b.WriteString(line[pos:start])
+			b.WriteString(listCellStyle(i, rows[0][col], cell).Render(cell))
+			pos = start + len(cell)
+		}
+		b.WriteString(line[pos:])
+		fmt.Println(b.String())
+	}
+	return nil
+}
+
+// listCellStyle returns the style of the cell in the column named column of
+// row (0 being the header) of the list table.
+func listCellStyle(row int, column, cell string) lipgloss.Style {
+	switch {
+	case row == 0:
+		return listHeaderStyle
+	case cell == "-":
+		return listDimStyle
+	}
+	switch column {
+	case "PATH", "AGE":
+		return listDimStyle
+	case "COMMIT":
+		return listCommitStyle
+	case "STATUS":
+		if cell == "dirty" {
+			return listDirtyStyle
+		}
+		return listDimStyle
+	case "LOCKED":
+		return listLockStyle
+	}
+	return lipgloss.NewStyle()
+}
+
+// parseAge parses a duration in days or weeks, like "30d" or "2w", or any
+// duration time.ParseDuration accepts, like "12h".
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// staleWorktrees returns the worktrees other than the main worktree at
+// mainPath whose last commit is older than age, with the date of that commit
+// keyed by path. Worktrees whose last commit can't be read are left out.
+func staleWorktrees(worktrees []worktree.Worktree, mainPath string, age time.Duration) ([]worktree.Worktree, map[string]time.Time) {
+	cutoff := time.Now().Add(-age)
+	var result []worktree.Worktree
+	dates := make(map[string]time.Time)
+	for _, wt := range worktrees {
+		if wt.Path == mainPath || wt.Bare {
+			continue
+		}
+		date, err := worktree.LastCommitDate(wt.Path)
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+		result = append(result, wt)
+		dates[wt.Path] = date
+	}
+	return result, dates
+}
+
+// sortWorktrees returns the worktrees sorted by mode (see listSorts). For
+// age, worktrees whose last commit can't be read, like a bare repository,
+// come last.
+func sortWorktrees(worktrees []worktree.Worktree, mode string) []worktree.Worktree {
+	sorted := slices.Clone(worktrees)
+	switch mode {
+	case "name":
+		slices.SortStableFunc(sorted, func(a, b worktree.Worktree) int {
+			return strings.Compare(a.Branch, b.Branch)
+		})
+	case "path":
+		slices.SortStableFunc(sorted, func(a, b worktree.Worktree) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+	case "age":
+		dates := make(map[string]time.Time, len(sorted))
+		for _, wt := range sorted {
+			if !wt.Bare {
+				dates[wt.Path], _ = worktree.LastCommitDate(wt.Path)
+			}
+		}
+		slices.SortStableFunc(sorted, func(a, b worktree.Worktree) int {
+			return dates[b.Path].Compare(dates[a.Path])
+		})
+	}
+	return sorted
+}
+
+// filterWorktrees returns the worktrees matching filter (see listFilters).
+// Worktrees whose status can't be determined are left out, as are the main
+// worktree at mainPath and a bare repository for merged and unmerged.
+func filterWorktrees(worktrees []worktree.Worktree, mainPath, filter string) []worktree.Worktree {
+	var result []worktree.Worktree
+	for _, wt := range worktrees {
+		var match bool
+		switch filter {
+		case "dirty", "clean":
+			dirty, err := worktree.IsDirty(wt.Path)
+			if err != nil {
+				continue
+			}
+			match = dirty == (filter == "dirty")
+		case "merged", "unmerged":
+			if wt.Path == mainPath || wt.Bare || wt.Branch == "(detached)" {
+				continue
+			}
+			merged, err := worktree.IsMerged(wt.Branch)
+			if err != nil {
+				continue
+			}
+			match = merged == (filter == "merged")
+		}
+		if match {
+			result = append(result, wt)
+		}
+	}
+	return result
+}
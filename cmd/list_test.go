@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+func TestFilterWorktreesBareClone(t *testing.T) {
+	isolateGit(t)
+	dir := tempDir(t)
+	src := filepath.Join(dir, "src")
+	git(t, dir, "init", "--quiet", "--initial-branch=main", src)
+	git(t, src, "commit", "--quiet", "--allow-empty", "-m", "initial")
+
+	// The bare repository is listed first, before main
+	bare := filepath.Join(dir, "repo.git")
+	git(t, dir, "clone", "--quiet", "--bare", src, bare)
+	git(t, bare, "worktree", "add", "--quiet", filepath.Join(dir, "main"), "main")
+	git(t, bare, "worktree", "add", "--quiet", "-b", "merged", filepath.Join(dir, "merged"))
+	git(t, bare, "worktree", "add", "--quiet", "-b", "unmerged", filepath.Join(dir, "unmerged"))
+	git(t, filepath.Join(dir, "unmerged"), "commit", "--quiet", "--allow-empty", "-m", "work")
+	t.Chdir(filepath.Join(dir, "main"))
+
+	repo := worktree.NewRepo()
+	worktrees, err := repo.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainPath, err := repo.MainWorktreePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"merged", []string{"merged"}},
+		{"unmerged", []string{"unmerged"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, wt := range filterWorktrees(worktrees, mainPath, tt.filter) {
+			got = append(got, wt.Branch)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("--filter %s = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var lockReason string
+
+var lockCmd = &cobra.Command{
+	Use:   "lock <branch>",
+	Short: "Lock a worktree so it isn't pruned or removed",
+	Long: `Lock the worktree of a branch with git worktree lock.
+
+Git won't prune, move or remove a locked worktree, which keeps worktrees on
+removable media or network shares from being pruned while they are
+unavailable. Use --reason to record why; it is shown by 'wk list'.
+
+Use 'wk unlock' to unlock it again.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeBranches,
+	RunE:              runLock,
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.Flags().StringVar(&lockReason, "reason", "", "Why the worktree is locked")
+}
+
+func runLock(cmd *cobra.Command, args []string) error {
+	wt, err := findWorktree(args[0])
+	if err != nil {
+		return err
+	}
+	if wt.Locked {
+		return fmt.Errorf("worktree '%s' is already locked", wt.Branch)
+	}
+
+	if err := worktree.Lock(wt.Path, lockReason); err != nil {
+		return err
+	}
+
+	fmt.Printf("Locked worktree '%s' at %s\n", wt.Branch, wt.Path)
+	return nil
+}
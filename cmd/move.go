@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var moveCmd = &cobra.Command{
+	Use:   "move <branch> <path>",
+	Short: "Move a worktree to another path",
+	Long: `Move the worktree of a branch to an explicit path, e.g. on a faster disk.
+
+The destination must not exist or be inside an existing worktree; missing
+parent directories are created. Moves across filesystems copy the worktree
+and verify the copy before removing the original.
+
+The main worktree cannot be moved, and a worktree cannot be moved while it is
+the current directory. Use 'wk organize' to move worktrees back to the
+standard location.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeMoveArgs,
+	RunE:              runMove,
+}
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+}
+
+func runMove(cmd *cobra.Command, args []string) error {
+	branch, dest := args[0], args[1]
+
+	wt, err := worktree.FindByBranch(branch)
+	if err != nil {
+		return err
+	}
+
+	mainPath, err := worktree.GetMainWorktreePath()
+	if err != nil {
+		return err
+	}
+	if wt.Path == mainPath {
+		return fmt.Errorf("cannot move the main worktree")
+	}
+
+	inside, err := worktree.IsCurrentDirInside(wt.Path)
+	if err != nil {
+		return err
+	}
+	if inside {
+		return fmt.Errorf("cannot move worktree '%s' while it is the current directory\n\nRun this command from another worktree (e.g. %s)", branch, mainPath)
+	}
+
+	output.Printf("Moving '%s' to %s...\n", branch, dest)
+	newPath, err := worktree.MoveTo(*wt, dest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Worktree '%s' is now at %s\n", branch, newPath)
+	return nil
+}
@@ -1,23 +1,31 @@
 package cmd
 
 import (
-	"bufio"
-	"errors"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
 	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/exit"
 	"github.com/lucas-stellet/wk/internal/hooks"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/selector"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
 var newCmd = &cobra.Command{
-	Use:   "new [branch]",
+	Use:   "new [branch | --detach [ref]]",
 	Short: "Create a new worktree",
 	Long: `Create a new git worktree and run post-creation hooks.
 
@@ -26,31 +34,200 @@ branch or create a new one.
 
 This command:
   1. Creates a new worktree using git worktree add
-  2. Copies files listed in .wk.yaml
-  3. Runs post_hooks from .wk.yaml`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runNew,
+  2. Applies the stash given by --from-stash, if any
+  3. Copies files listed in .wk.yaml
+  4. Runs post_hooks from .wk.yaml
+  5. Runs verify checks from .wk.yaml
+
+--no-copy skips copying and linking files, and --no-hooks skips post_hooks,
+verify checks and post_create_main; with both, only the worktree is created.
+
+A failing verify check means the worktree was set up but doesn't work; it is
+reported separately, and with remove_on_verify_failure the worktree (and its
+branch, if this command created it) is removed again.
+
+If creating or setting up the worktree fails before the verify checks (e.g.
+a file can't be copied or a post hook fails), the worktree is removed again,
+along with its branch if this command created it; --keep-on-failure keeps
+both to look into the failure. A worktree git already had registered at the
+path is never removed.
+
+Interrupting the setup (Ctrl+C) kills the running command and asks whether to
+remove the half set up worktree the same way; --rollback-on-interrupt removes
+it without asking. When stdin is not a terminal it is kept unless that flag is
+given.
+
+New branches start from the current HEAD, or from default_base if it is set
+in .wk.yaml; use --from-default to start them from the repository's default
+branch (the remote's HEAD, or main/master), or --from to start them from any
+branch, tag or commit. Both flags take precedence over default_base.
+
+With --track remote/branch (e.g. --track origin/feature-x), the worktree's
+branch tracks that remote branch. The local branch is named after the remote
+one unless branch is given; it is created from the remote branch if missing,
+and its upstream is set and checked either way.
+
+With --detach, the worktree is created at ref (default HEAD) with a detached
+HEAD instead of a branch, in a directory named after the short commit.
+
+Files are copied and linked from the current worktree, and its configuration
+is used; --copy-from uses the worktree of another branch (e.g. main) instead.
+
+If the current worktree has uncommitted changes, wk notes that they stay
+behind and asks whether to continue (only when stdin is a terminal).
+
+If the worktree's directory was left behind by an earlier worktree, use
+--force: wk offers to remove the directory first and passes --force to git
+worktree add. A directory that git still has registered as a worktree is
+only removed after confirming that too.
+
+Afterwards it asks whether to open a shell in the new worktree. Use --switch
+or --no-switch to decide without asking; when stdin is not a terminal and
+neither is given, it doesn't switch.
+
+With --open, the new worktree is opened in your editor (chosen as for
+'wk open') instead of asking. Add --switch to also open a shell in it once
+the editor is started, or once a terminal editor exits. If the branch already
+has a worktree, --open opens that one.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeNewBranches,
+	RunE:              runNew,
 }
 
+var (
+	newFromStash           string
+	newSort                string
+	newSwitch              bool
+	newNoSwitch            bool
+	newDetach              bool
+	newFromDefault         bool
+	newCopyFrom            string
+	newForce               bool
+	newTrack               string
+	newFrom                string
+	newOpen                bool
+	newRollbackOnInterrupt bool
+	newNoHooks             bool
+	newNoCopy              bool
+	newKeepOnFailure       bool
+)
+
 func init() {
 	rootCmd.AddCommand(newCmd)
+	newCmd.Flags().StringVar(&newFromStash, "from-stash", "", "Apply the given stash (e.g. stash@{0}) in the new worktree")
+	newCmd.Flags().StringVar(&newSort, "sort", "", "Order branches in the selector by name, date or status")
+	newCmd.Flags().BoolVar(&newSwitch, "switch", false, "Switch to the new worktree without asking")
+	newCmd.Flags().BoolVar(&newNoSwitch, "no-switch", false, "Don't switch to the new worktree and don't ask")
+	newCmd.Flags().BoolVar(&newNoHooks, "no-hooks", false, "Don't run post hooks, verify checks or post_create_main")
+	newCmd.Flags().BoolVar(&newNoCopy, "no-copy", false, "Don't copy or link files into the new worktree")
+	newCmd.Flags().BoolVar(&newKeepOnFailure, "keep-on-failure", false, "Keep the new worktree and branch if creating or setting it up fails")
+	newCmd.Flags().BoolVar(&newRollbackOnInterrupt, "rollback-on-interrupt", false, "Remove the worktree without asking if its setup is interrupted")
+	newCmd.Flags().BoolVar(&newOpen, "open", false, "Open the new worktree in your editor instead of asking to switch")
+	newCmd.Flags().BoolVar(&newDetach, "detach", false, "Create a worktree with a detached HEAD at ref instead of a branch")
+	newCmd.Flags().BoolVar(&newFromDefault, "from-default", false, "Create a new branch from the default branch instead of HEAD")
+	newCmd.Flags().StringVar(&newFrom, "from", "", "Create a new branch from this ref instead of HEAD")
+	newCmd.RegisterFlagCompletionFunc("from", completeRefs)
+	newCmd.Flags().StringVar(&newTrack, "track", "", "Make the branch track this remote branch (e.g. origin/feature-x)")
+	newCmd.Flags().BoolVarP(&newForce, "force", "f", false, "Create the worktree even if its directory already exists")
+	newCmd.Flags().StringVar(&newCopyFrom, "copy-from", "", "Copy and link files from the worktree of this branch instead of the current one")
+	newCmd.RegisterFlagCompletionFunc("copy-from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeWorktreeBranches(cmd, nil, toComplete)
+	})
+	newCmd.MarkFlagsMutuallyExclusive("switch", "no-switch")
+	newCmd.MarkFlagsMutuallyExclusive("detach", "from-default", "from", "track")
 }
 
 func runNew(cmd *cobra.Command, args []string) error {
 	var branch string
+	base := "HEAD"
 
-	if len(args) == 1 {
-		branch = args[0]
-	} else {
+	sortMode := selector.SortMode(newSort)
+	if newSort != "" && !slices.Contains(selector.SortModes, sortMode) {
+		return fmt.Errorf("invalid --sort %q: must be name, date or status", newSort)
+	}
+
+	// One repository cache for the whole command, so the worktree list and
+	// repository name are queried once
+	repo := worktree.NewRepo()
+
+	// Source worktree for files and configuration
+	srcDir, err := copySource(repo)
+	if err != nil {
+		return err
+	}
+
+	// Base for new branches given by --from-default or --from
+	var flagBase, baseFlag string
+	switch {
+	case newFromDefault:
+		var err error
+		flagBase, err = worktree.DefaultBase()
+		if err != nil {
+			return err
+		}
+		baseFlag = "--from-default"
+	case newFrom != "":
+		if _, _, err := worktree.ResolveRevision(newFrom); err != nil {
+			return exit.WithCode(exit.NotFound, fmt.Errorf("--from: '%s' is not a valid revision", newFrom))
+		}
+		flagBase, baseFlag = newFrom, "--from"
+	}
+
+	// Base for new branches from default_base, used without those flags
+	var configBase string
+	if flagBase == "" && !newDetach && newTrack == "" {
+		configBase, err = defaultBase(srcDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	detachRef := "HEAD"
+	var trackRemote, trackBranch string
+	// Selections made interactively are confirmed before creating anything
+	confirmPlan := false
+	switch {
+	case newDetach:
+		if len(args) == 1 {
+			detachRef = args[0]
+		}
+	case newTrack != "":
+		var err error
+		trackRemote, trackBranch, err = worktree.ResolveUpstream(newTrack)
+		if err != nil {
+			return err
+		}
+		branch = trackBranch
+		if len(args) == 1 {
+			branch = args[0]
+		}
+	case len(args) == 1:
+		var err error
+		branch, base, err = resolveNewTarget(args[0])
+		if err != nil {
+			return err
+		}
+		switch {
+		case flagBase != "":
+			if base != "HEAD" {
+				return fmt.Errorf("%s cannot be used with a revision", baseFlag)
+			}
+			if worktree.PlanAdd(branch) != worktree.AddNew {
+				output.Printf("Branch '%s' already exists; %s only applies to new branches\n", branch, baseFlag)
+			}
+			base = flagBase
+		case base == "HEAD" && configBase != "":
+			// A revision given as the argument is a more specific base
+			base = configBase
+		}
+	default:
 		// Interactive mode: select from branches or create new
 		selected, isNew, err := selector.SelectOrCreate(selector.Options{
 			AllowCreate:    true,
 			FilterExisting: true, // don't show branches that already have worktrees
+			Sort:           sortMode,
 		})
 		if err != nil {
-			if errors.Is(err, selector.ErrCancelled) {
-				return nil
-			}
 			return err
 		}
 
@@ -62,80 +239,599 @@ func runNew(cmd *cobra.Command, args []string) error {
 		} else {
 			branch = selected
 		}
+
+		switch {
+		case flagBase != "":
+			base = flagBase
+		case configBase != "":
+			base = configBase
+		}
+		confirmPlan = true
 	}
 
-	// Get current directory (source worktree)
-	srcDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+	if !newDetach {
+		exists, path, err := repo.HasWorktree(branch)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return switchToExisting(branch, path)
+		}
+	}
+
+	if confirmPlan && !confirmAddPlan(branch, base) {
+		output.Println("Aborted")
+		return nil
+	}
+
+	stashRef := normalizeStashRef(newFromStash)
+	if stashRef != "" && !worktree.StashExists(stashRef) {
+		return fmt.Errorf("stash '%s' not found\n\nRun 'git stash list' to see available stashes", newFromStash)
+	}
+
+	if stashRef == "" && !confirmDirtySource() {
+		output.Println("Aborted")
+		return nil
+	}
+
+	if newForce {
+		cleared, err := clearWorktreePath(repo, branch, detachRef)
+		if err != nil {
+			return err
+		}
+		if !cleared {
+			output.Println("Aborted")
+			return nil
+		}
+	}
+
+	// A worktree git still has registered at the path (re-added with
+	// --force) predates this command, so it is kept if anything fails
+	removable := !newKeepOnFailure
+	if path, err := newWorktreePath(repo, branch, detachRef); err != nil || isRegistered(repo, path) {
+		removable = false
 	}
 
 	// Create worktree
-	fmt.Printf("Creating worktree for branch '%s'...\n", branch)
-	dstDir, err := worktree.Add(branch)
+	var dstDir string
+	var createdBranch bool
+	if newDetach {
+		output.Printf("Creating detached worktree at %s...\n", detachRef)
+		dstDir, err = repo.AddDetached(detachRef, newForce)
+		if dstDir != "" {
+			// Name the worktree by its commit in messages and hooks
+			branch = filepath.Base(dstDir)
+		}
+	} else {
+		createdBranch = worktree.PlanAdd(branch) != worktree.AddExisting
+		output.Printf("Creating worktree for branch '%s'...\n", branch)
+		if trackRemote != "" {
+			dstDir, err = repo.AddTrack(branch, trackRemote, trackBranch, newForce)
+		} else {
+			dstDir, err = repo.AddFrom(branch, base, newForce)
+		}
+	}
+	if err != nil {
+		if dstDir == "" {
+			return err
+		}
+		// git created the worktree, but a check after it failed
+		return removeFailed(dstDir, branch, createdBranch, removable, err)
+	}
+	output.Printf("Created worktree at %s\n", dstDir)
+	if trackRemote != "" {
+		output.Printf("Branch '%s' tracks %s/%s\n", branch, trackRemote, trackBranch)
+	}
+
+	// Ctrl+C (or SIGTERM) during setup kills the running command; the
+	// half set up worktree can then be removed (see rollbackInterrupted)
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg, err := setUpNew(ctx, repo, branch, dstDir, srcDir, stashRef, createdBranch, removable)
+	if ctx.Err() != nil {
+		stop()
+		return rollbackInterrupted(dstDir, branch, createdBranch)
+	}
+	stop()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Created worktree at %s\n", dstDir)
 
-	// Load config
-	configPath, err := config.FindConfig(srcDir)
-	if os.IsNotExist(err) {
-		fmt.Println("No .wk.yaml found, skipping hooks")
+	output.Printf("\nWorktree '%s' is ready!\n", branch)
+
+	if newOpen {
+		if err := openInEditor(dstDir); err != nil {
+			return err
+		}
+		if !newSwitch {
+			return nil
+		}
+	}
+
+	if shouldSwitch("Switch to new worktree?") {
+		output.Printf("Switching to worktree '%s'...\n", branch)
+		output.Println("Type 'exit' to return to the previous shell.")
+		// Without a config there is no shell_env, only the WK_* variables
+		var shellEnv map[string]string
+		if cfg != nil {
+			shellEnv = cfg.ShellEnv
+		}
+		env, err := hookEnv(branch, dstDir, srcDir).ShellVars(shellEnv)
+		if err != nil {
+			return err
+		}
+		return openNewShellAt(dstDir, env)
+	}
+
+	return nil
+}
+
+// switchToExisting offers to switch to the worktree branch already has,
+// instead of failing to create a second one. --open opens it in the editor
+// as it would a new worktree.
+func switchToExisting(branch, path string) error {
+	output.Printf("Branch '%s' already has a worktree at %s\n", branch, path)
+	if newOpen {
+		if err := openInEditor(path); err != nil {
+			return err
+		}
+		if !newSwitch {
+			return nil
+		}
+	}
+	if !shouldSwitch("Switch to it?") {
 		return nil
 	}
+	return runSwitch(switchCmd, []string{branch})
+}
+
+// copySource returns the worktree files are copied from: the worktree of the
+// --copy-from branch, or the current directory.
+func copySource(repo *worktree.Repo) (string, error) {
+	if newCopyFrom == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("get working directory: %w", err)
+		}
+		return wd, nil
+	}
+
+	wt, err := repo.FindByBranch(newCopyFrom)
+	if err != nil {
+		return "", fmt.Errorf("--copy-from: branch '%s' has no worktree", newCopyFrom)
+	}
+	return wt.Path, nil
+}
+
+// copyList returns the static copy list followed by the paths printed by
+// copy_command, if set, without duplicates. copy_command is killed if ctx is
+// done first.
+func copyList(ctx context.Context, cfg *config.Config, srcDir string, env hooks.Env) ([]config.CopyEntry, error) {
+	if cfg.CopyCommand == "" {
+		return cfg.Copy, nil
+	}
+
+	extra, err := hooks.CopyCommandFiles(ctx, srcDir, cfg.CopyCommand, env)
 	if err != nil {
-		return fmt.Errorf("find config: %w", err)
+		return nil, err
 	}
 
-	cfg, err := config.Load(configPath)
+	files := slices.Clone(cfg.Copy)
+	for _, f := range extra {
+		if entry := (config.CopyEntry{From: f}); !slices.Contains(files, entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+// clearWorktreePath offers to remove whatever is left at the path the new
+// worktree will use, for --force. Returns false if the user declines. A
+// directory git still has registered as a worktree is removed with git,
+// after a separate confirmation naming it, unless it belongs to another
+// branch.
+func clearWorktreePath(repo *worktree.Repo, branch, detachRef string) (bool, error) {
+	path, err := newWorktreePath(repo, branch, detachRef)
 	if err != nil {
-		return fmt.Errorf("load config: %w", err)
+		return false, err
 	}
 
+	owner := branch
+	if newDetach {
+		owner = "(detached)"
+	}
+	if err := repo.CheckPath(path, owner); err != nil {
+		return false, err
+	}
+
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return true, nil
+	}
+
+	if wt, err := repo.FindByPath(path); err == nil {
+		name := wt.Branch
+		if name == "" {
+			name = "detached"
+		}
+		if !prompt.Confirm(fmt.Sprintf("%s is still a registered worktree (%s). Remove it with its changes?", path, name)) {
+			return false, nil
+		}
+		output.Printf("Removing worktree at %s...\n", path)
+		defer repo.Invalidate()
+		return true, worktree.Remove(path, true)
+	}
+
+	if !prompt.Confirm(fmt.Sprintf("%s already exists and is not a worktree. Remove it?", path)) {
+		return false, nil
+	}
+	output.Printf("Removing %s...\n", path)
+	if err := os.RemoveAll(path); err != nil {
+		return false, fmt.Errorf("remove %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// newWorktreePath returns the path 'wk new' creates the worktree at: that of
+// branch, or with --detach that of detachRef.
+func newWorktreePath(repo *worktree.Repo, branch, detachRef string) (string, error) {
+	if newDetach {
+		return repo.DetachedPath(detachRef)
+	}
+	return repo.StandardPath(branch)
+}
+
+// isRegistered reports whether git has a worktree registered at path, even
+// if its directory is missing.
+func isRegistered(repo *worktree.Repo, path string) bool {
+	_, err := repo.FindByPath(path)
+	return err == nil
+}
+
+// setUpNew applies the stash, copies and links files and runs the hooks and
+// checks for the worktree just created at dstDir. Returns a nil config if
+// there is none. When ctx is done, the running command is killed and setup
+// stops with an error. If a step before the verify checks fails, the
+// worktree is removed when removable (see removeFailed).
+func setUpNew(ctx context.Context, repo *worktree.Repo, branch, dstDir, srcDir, stashRef string, createdBranch, removable bool) (*config.Config, error) {
+	failed := func(err error) (*config.Config, error) {
+		if ctx.Err() != nil {
+			// Interrupted; see rollbackInterrupted
+			return nil, err
+		}
+		return nil, removeFailed(dstDir, branch, createdBranch, removable, err)
+	}
+
+	if stashRef != "" {
+		output.Printf("Applying %s...\n", stashRef)
+		if err := worktree.ApplyStash(dstDir, stashRef); err != nil {
+			return failed(err)
+		}
+	}
+
+	// Load config (global config merged with .wk.yaml)
+	res, err := config.Resolve(srcDir)
+	if os.IsNotExist(err) {
+		output.Println("No .wk.yaml found, skipping hooks")
+		return nil, nil
+	}
+	if err != nil {
+		return failed(fmt.Errorf("load config: %w", err))
+	}
+	cfg := res.Config
+
+	if output.IsVerbose() {
+		printResolution(res)
+	}
+	printSkippedSteps(newNoCopy, newNoHooks)
+	env := hookEnv(branch, dstDir, srcDir)
+
 	// Copy files
-	if len(cfg.Copy) > 0 {
-		fmt.Println("\nCopying files...")
-		if err := hooks.CopyFiles(srcDir, dstDir, cfg.Copy); err != nil {
-			return fmt.Errorf("copy files: %w", err)
+	if !newNoCopy && (len(cfg.Copy) > 0 || cfg.CopyCommand != "") {
+		output.Println("\nCopying files...")
+		files, err := copyList(ctx, cfg, srcDir, env)
+		if err != nil {
+			return failed(err)
+		}
+		if cfg.SkipTracked != nil && *cfg.SkipTracked {
+			files = skipTrackedFiles(srcDir, dstDir, files)
+		}
+		if err := hooks.CopyFiles(srcDir, dstDir, files, cfg.DirCopyMode); err != nil {
+			return failed(fmt.Errorf("copy files: %w", err))
+		}
+	}
+
+	// Link files
+	if !newNoCopy && len(cfg.Link) > 0 {
+		output.Println("\nLinking files...")
+		if err := hooks.LinkFiles(srcDir, dstDir, cfg.Link); err != nil {
+			return failed(fmt.Errorf("link files: %w", err))
 		}
 	}
 
 	// Run post hooks
-	if len(cfg.PostHooks) > 0 {
-		fmt.Println("\nRunning post hooks...")
-		if err := hooks.RunPostHooks(dstDir, cfg.PostHooks); err != nil {
-			return fmt.Errorf("run hooks: %w", err)
+	if !newNoHooks && len(cfg.PostHooks) > 0 {
+		output.Println("\nRunning post hooks...")
+		if err := hooks.RunPostHooks(ctx, dstDir, cfg.PostHooks, env, false); err != nil {
+			return failed(fmt.Errorf("run hooks: %w", err))
 		}
 	}
 
-	fmt.Printf("\nWorktree '%s' is ready!\n", branch)
+	// Run verify checks
+	if !newNoHooks && len(cfg.Verify) > 0 {
+		output.Println("\nVerifying worktree...")
+		if err := hooks.RunVerify(ctx, dstDir, cfg.Verify, env); err != nil {
+			if ctx.Err() != nil || cfg.RemoveOnVerifyFailure == nil || !*cfg.RemoveOnVerifyFailure {
+				return nil, fmt.Errorf("worktree '%s' was created at %s but failed verification: %w", branch, dstDir, err)
+			}
+			if rmErr := removeNew(dstDir, branch, createdBranch); rmErr != nil {
+				return nil, fmt.Errorf("worktree '%s' failed verification: %w (removing it failed: %v)", branch, err, rmErr)
+			}
+			return nil, fmt.Errorf("worktree '%s' failed verification and was removed: %w", branch, err)
+		}
+	}
 
-	if confirmSwitchPrompt() {
-		fmt.Printf("Switching to worktree '%s'...\n", branch)
-		fmt.Println("Type 'exit' to return to the previous shell.")
-		return openNewShellAt(dstDir)
+	// Run post_create_main hooks in the main worktree
+	if !newNoHooks && len(cfg.PostCreateMain) > 0 {
+		mainPath, err := repo.MainWorktreePath()
+		if err != nil {
+			return nil, fmt.Errorf("get main worktree: %w", err)
+		}
+		output.Printf("\nRunning post_create_main hooks in main worktree (%s)...\n", mainPath)
+		if err := hooks.RunMainHooks(ctx, mainPath, cfg.PostCreateMain, env); err != nil {
+			return nil, fmt.Errorf("run post_create_main hooks: %w", err)
+		}
 	}
 
+	return cfg, nil
+}
+
+// printSkippedSteps notes the setup steps skipped by --no-copy and
+// --no-hooks, so a bare worktree doesn't come as a surprise.
+func printSkippedSteps(noCopy, noHooks bool) {
+	var skipped []string
+	if noCopy {
+		skipped = append(skipped, "file copy and links (--no-copy)")
+	}
+	if noHooks {
+		skipped = append(skipped, "hooks and verify checks (--no-hooks)")
+	}
+	if len(skipped) > 0 {
+		output.Printf("Skipping %s\n", strings.Join(skipped, ", "))
+	}
+}
+
+// rollbackInterrupted removes the worktree at dir, and branch if wk created
+// it, after its setup was interrupted: with --rollback-on-interrupt, or if
+// confirmed when stdin is a terminal. Otherwise the worktree is kept as is.
+// The outcome is printed here; the returned error only sets the exit code.
+func rollbackInterrupted(dir, branch string, createdBranch bool) error {
+	interrupted := exit.WithCode(exit.Cancelled, fmt.Errorf("setup of worktree '%s' was interrupted", branch))
+	fmt.Fprintf(os.Stderr, "\nInterrupted while setting up worktree '%s'\n", branch)
+	if !newRollbackOnInterrupt && (!term.IsTerminal(os.Stdin.Fd()) || !prompt.Confirm("Remove the partially set up worktree?")) {
+		fmt.Fprintf(os.Stderr, "Kept it at %s; run 'wk setup' there to finish setting it up\n", dir)
+		return interrupted
+	}
+	if err := removeNew(dir, branch, createdBranch); err != nil {
+		return fmt.Errorf("setup of worktree '%s' was interrupted; removing it failed: %w", branch, err)
+	}
+	output.Printf("Worktree '%s' removed\n", branch)
+	return interrupted
+}
+
+// removeFailed removes the worktree at dir after creating or setting it up
+// failed with err, and branch if it was created for the worktree, unless
+// removable is false (with --keep-on-failure, or for a worktree that was
+// registered before). Returns err with a note on what became of the worktree.
+func removeFailed(dir, branch string, createdBranch, removable bool, err error) error {
+	if !removable {
+		return fmt.Errorf("%w\n\nThe worktree was kept at %s", err, dir)
+	}
+	if rmErr := removeNew(dir, branch, createdBranch); rmErr != nil {
+		return fmt.Errorf("%w\n\nRemoving the worktree at %s failed: %v", err, dir, rmErr)
+	}
+	return fmt.Errorf("%w\n\nThe worktree was removed; use --keep-on-failure to keep it", err)
+}
+
+// removeNew removes the worktree at dir after a failed setup or verify check
+// or an interrupted setup, and deletes branch if it was created for the
+// worktree.
+func removeNew(dir, branch string, createdBranch bool) error {
+	output.Printf("Removing worktree at %s...\n", dir)
+	if err := worktree.Remove(dir, true); err != nil {
+		return err
+	}
+	if createdBranch {
+		return worktree.DeleteBranch(branch, true)
+	}
 	return nil
 }
 
-func confirmSwitchPrompt() bool {
-	fmt.Print("Switch to new worktree? [y/N]: ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
-	return input == "y" || input == "yes"
+// defaultBase returns default_base from the configuration used for srcDir,
+// or "" if it isn't set. It must be a valid revision.
+func defaultBase(srcDir string) (string, error) {
+	cfg, err := config.LoadMerged(srcDir)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load config: %w", err)
+	}
+	if cfg.DefaultBase == "" {
+		return "", nil
+	}
+	if _, _, err := worktree.ResolveRevision(cfg.DefaultBase); err != nil {
+		return "", exit.WithCode(exit.InvalidConfig, fmt.Errorf("default_base: '%s' is not a valid revision", cfg.DefaultBase))
+	}
+	return cfg.DefaultBase, nil
+}
+
+// resolveNewTarget returns the branch and base for 'wk new arg'. Existing
+// branches and new names are used as-is. Revisions like "@{-1}" that name a
+// branch resolve to it, unless that is the current branch; other revisions
+// like "HEAD~2" or a tag become the base of a new branch whose name is
+// prompted for.
+func resolveNewTarget(arg string) (branch, base string, err error) {
+	if worktree.BranchExists(arg) {
+		return arg, "HEAD", nil
+	}
+
+	resolved, commit, err := worktree.ResolveRevision(arg)
+	if err != nil {
+		// Not a revision: a new branch name
+		return arg, "HEAD", nil
+	}
+
+	if resolved != "" {
+		if current, err := worktree.GetCurrentBranch(); err == nil && current == resolved {
+			return "", "", fmt.Errorf("'%s' is '%s', the branch checked out in this worktree\n\nTo branch off it, name the new branch: wk new <branch> --from %s", arg, resolved, arg)
+		}
+		output.Printf("Resolved '%s' to branch '%s'\n", arg, resolved)
+		return resolved, "HEAD", nil
+	}
+
+	if worktree.TagExists(arg) {
+		output.Printf("'%s' is a tag at commit %s; the worktree needs a new branch from it\n", arg, commit[:7])
+		output.Hintf("use --detach to check out the tag without a branch\n")
+	} else {
+		output.Printf("'%s' resolves to commit %s\n", arg, commit[:7])
+	}
+	branch, err = selector.PromptForBranchName()
+	if err != nil {
+		return "", "", err
+	}
+	return branch, commit, nil
+}
+
+// confirmAddPlan prints whether a worktree for branch will check out an
+// existing branch, track a remote one or create a new branch from base, and
+// asks to proceed.
+func confirmAddPlan(branch, base string) bool {
+	switch worktree.PlanAdd(branch) {
+	case worktree.AddExisting:
+		output.Printf("Will create worktree for existing branch '%s'\n", branch)
+	case worktree.AddTracking:
+		output.Printf("Will create worktree tracking %s/%s\n", worktree.TrackingRemote(branch), branch)
+	default:
+		if base == "HEAD" {
+			if current, err := worktree.GetCurrentBranch(); err == nil && current != "HEAD" {
+				base = current
+			}
+		}
+		output.Printf("Will create new branch '%s' from %s\n", branch, base)
+	}
+	return prompt.Confirm("Proceed?")
+}
+
+// confirmDirtySource notes uncommitted changes in the current worktree, which
+// stay there rather than carrying over to the new one, and asks whether to
+// proceed. It only asks when stdin is a terminal, and defaults to yes.
+func confirmDirtySource() bool {
+	dirty, err := worktree.HasUncommittedChanges()
+	if err != nil || !dirty {
+		return true
+	}
+
+	current, err := worktree.GetCurrentBranch()
+	if err != nil {
+		current = "HEAD"
+	}
+	output.Printf("Note: '%s' has uncommitted changes. They stay in this worktree and won't be in the new one.\n", current)
+	output.Hintf("stash them and pass --from-stash to bring them along\n")
+
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return true
+	}
+	return prompt.ConfirmDefaultYes("Continue?")
 }
 
-func openNewShellAt(dir string) error {
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "bash"
+// hookEnv builds the WK_* environment for hooks run in dstDir.
+func hookEnv(branch, dstDir, srcDir string) hooks.Env {
+	repoName, _ := worktree.GetRepoName()
+	return hooks.Env{
+		Branch:       branch,
+		WorktreePath: dstDir,
+		SourcePath:   srcDir,
+		RepoName:     repoName,
+	}
+}
+
+// printResolution lists the loaded config files and the file each copy,
+// link and hook entry came from.
+func printResolution(res *config.Resolution) {
+	output.Println("\nConfig files:")
+	for _, f := range res.Files {
+		output.Printf("  %s\n", f)
+	}
+
+	printEntries := func(field string, values []string) {
+		for _, v := range values {
+			output.Printf("  %s %s (from %s)\n", field, v, res.Origin(field, v))
+		}
+	}
+	copies := make([]string, len(res.Config.Copy))
+	for i, e := range res.Config.Copy {
+		copies[i] = e.String()
+	}
+	printEntries("copy", copies)
+	printEntries("link", res.Config.Link)
+	for _, h := range res.Config.PostHooks {
+		output.Printf("  post_hooks %s (from %s)\n", h.Run, res.Origin("post_hooks", h.Run))
 	}
+	for _, h := range res.Config.PostCreateMain {
+		output.Printf("  post_create_main %s (from %s)\n", h.Run, res.Origin("post_create_main", h.Run))
+	}
+	for _, h := range res.Config.Verify {
+		output.Printf("  verify %s (from %s)\n", h.Run, res.Origin("verify", h.Run))
+	}
+}
+
+// normalizeStashRef expands a bare stash index like "2" to "stash@{2}".
+func normalizeStashRef(ref string) string {
+	if _, err := strconv.Atoi(ref); err == nil {
+		return "stash@{" + ref + "}"
+	}
+	return ref
+}
+
+// skipTrackedFiles filters out regular files whose committed version is
+// already present in dstDir, printing a note for each skipped file. Entries
+// copied to another path are always kept.
+func skipTrackedFiles(srcDir, dstDir string, files []config.CopyEntry) []config.CopyEntry {
+	var result []config.CopyEntry
+	for _, entry := range files {
+		file := entry.From
+		if entry.Dest() != file {
+			result = append(result, entry)
+			continue
+		}
+		info, err := os.Stat(filepath.Join(srcDir, file))
+		if err == nil && info.Mode().IsRegular() && worktree.IsTrackedIdentical(srcDir, dstDir, file) {
+			output.Printf("  skipping %s (tracked, already in worktree)\n", file)
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// shouldSwitch reports whether to switch to a worktree, following --switch
+// and --no-switch or asking question when stdin is a terminal.
+func shouldSwitch(question string) bool {
+	switch {
+	case newSwitch:
+		return true
+	case newNoSwitch:
+		return false
+	case !term.IsTerminal(os.Stdin.Fd()):
+		return false
+	}
+	return prompt.Confirm(question)
+}
 
-	cmd := exec.Command(shell)
+func openNewShellAt(dir string, env []string) error {
+	cmd := exec.Command(userShell())
 	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/selector"
+)
+
+var openEditor string
+
+var openCmd = &cobra.Command{
+	Use:   "open [branch]",
+	Short: "Open a worktree in your editor",
+	Long: `Open a worktree in your editor.
+
+If branch is not specified, opens an interactive selector to choose a worktree.
+
+The editor is taken from --editor, then the 'editor' field in .wk.yaml, then
+$VISUAL, then $EDITOR, falling back to 'code' if it is installed and 'vi'
+otherwise. It may include arguments (e.g. "code -n").
+
+GUI editors such as VS Code are started in the background so the terminal stays
+usable; terminal editors such as vim run in the foreground.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeBranches,
+	RunE:              runOpen,
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+	openCmd.Flags().StringVarP(&openEditor, "editor", "e", "", "Editor command to use for this invocation")
+}
+
+// guiEditors are editors that open their own window, so wk doesn't wait for
+// them to exit.
+var guiEditors = map[string]bool{
+	"code":          true,
+	"code-insiders": true,
+	"codium":        true,
+	"cursor":        true,
+	"windsurf":      true,
+	"zed":           true,
+	"subl":          true,
+	"mate":          true,
+	"atom":          true,
+	"idea":          true,
+	"goland":        true,
+	"webstorm":      true,
+	"pycharm":       true,
+	"fleet":         true,
+	"gvim":          true,
+	"mvim":          true,
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	var branch string
+	if len(args) == 1 {
+		branch = args[0]
+	} else {
+		selected, err := selector.SelectWorktree()
+		if err != nil {
+			return err
+		}
+		branch = selected
+	}
+
+	wt, err := findWorktree(branch)
+	if err != nil {
+		return err
+	}
+	return openInEditor(wt.Path)
+}
+
+// openInEditor opens dir in the editor from resolveEditor, in the background
+// for GUI editors and in the foreground otherwise.
+func openInEditor(dir string) error {
+	fields := strings.Fields(resolveEditor())
+	if len(fields) == 0 {
+		return fmt.Errorf("no editor configured")
+	}
+
+	editorCmd := exec.Command(fields[0], append(fields[1:], dir)...)
+	editorCmd.Dir = dir
+
+	if guiEditors[filepath.Base(fields[0])] {
+		output.Printf("Opening %s in %s...\n", dir, fields[0])
+		if err := editorCmd.Start(); err != nil {
+			return fmt.Errorf("start editor: %w", err)
+		}
+		return editorCmd.Process.Release()
+	}
+
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+	return editorCmd.Run()
+}
+
+// resolveEditor returns the editor command: --editor, the config's editor,
+// $VISUAL, $EDITOR, then code if installed, otherwise vi.
+func resolveEditor() string {
+	if openEditor != "" {
+		return openEditor
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if cfg, err := config.LoadMerged(wd); err == nil && cfg.Editor != "" {
+			return cfg.Editor
+		}
+	}
+
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(env); editor != "" {
+			return editor
+		}
+	}
+
+	if _, err := exec.LookPath("code"); err == nil {
+		return "code"
+	}
+	return "vi"
+}
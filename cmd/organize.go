@@ -1,13 +1,11 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
@@ -23,7 +21,8 @@ func init() {
 }
 
 func runOrganize(cmd *cobra.Command, args []string) error {
-	worktrees, err := worktree.List()
+	repo := worktree.NewRepo()
+	worktrees, err := repo.List()
 	if err != nil {
 		return err
 	}
@@ -31,7 +30,7 @@ func runOrganize(cmd *cobra.Command, args []string) error {
 	// Find worktrees not in standard location
 	var nonStandard []worktree.Worktree
 	for _, wt := range worktrees {
-		isStandard, err := worktree.IsInStandardLocation(wt.Path)
+		isStandard, err := repo.IsInStandardLocation(wt.Path)
 		if err != nil {
 			continue
 		}
@@ -46,7 +45,7 @@ func runOrganize(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show what will be moved
-	worktreesDir, err := worktree.GetWorktreesDir()
+	worktreesDir, err := repo.WorktreesDir()
 	if err != nil {
 		return err
 	}
@@ -55,19 +54,15 @@ func runOrganize(cmd *cobra.Command, args []string) error {
 	for _, wt := range nonStandard {
 		fmt.Printf("  %s\n", wt.Branch)
 		fmt.Printf("    from: %s\n", wt.Path)
-		fmt.Printf("    to:   %s/%s\n\n", worktreesDir, wt.Branch)
+		if newPath, err := worktree.StandardPath(wt.Branch); err == nil {
+			fmt.Printf("    to:   %s\n\n", newPath)
+		} else {
+			fmt.Printf("    to:   %v\n\n", err)
+		}
 	}
 
 	// Ask for confirmation
-	fmt.Print("Proceed? [y/N] ")
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return err
-	}
-
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "y" && response != "yes" {
+	if !prompt.Confirm("Proceed?") {
 		fmt.Println("Aborted.")
 		return nil
 	}
@@ -1,55 +1,418 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/hooks"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/selector"
+	"github.com/lucas-stellet/wk/internal/state"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
-var removeForce bool
+var (
+	removeForce        bool
+	removeMulti        bool
+	removeDeleteBranch bool
+	removeFilter       string
+)
 
 var removeCmd = &cobra.Command{
 	Use:     "remove [branch]",
 	Aliases: []string{"rm"},
 	Short:   "Remove a worktree",
-	Long: `Remove a git worktree by branch name.
+	Long: `Remove a git worktree by branch name. If no worktree has that branch,
+offers the worktrees whose branch resembles it (e.g. feature/login for
+feat-login).
 
 If branch is not specified, opens an interactive selector to choose which
-worktree to remove.`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runRemove,
+worktree to remove.
+
+Use --multi to select several worktrees (space to toggle) and remove them all.
+
+Use --filter to remove all worktrees whose branch matches a glob pattern
+(e.g. 'experiment/*'). The main and current worktrees are never matched.
+
+Use --delete-branch to also delete the branch after removing its worktree.
+Branches not merged into the default branch are only deleted with --force.
+
+Before removing a single worktree, shows its uncommitted changes and how many
+commits its branch has that aren't pushed to any remote. A worktree with
+uncommitted changes is only removed with --force, which always asks for
+confirmation. Use the global --yes to skip confirmation prompts.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeBranches,
+	RunE:              runRemove,
 }
 
 func init() {
 	rootCmd.AddCommand(removeCmd)
 	removeCmd.Flags().BoolVarP(&removeForce, "force", "f", false, "Force removal even if worktree has uncommitted changes")
+	removeCmd.Flags().BoolVarP(&removeMulti, "multi", "m", false, "Select multiple worktrees to remove")
+	removeCmd.Flags().StringVar(&removeFilter, "filter", "", "Remove all worktrees whose branch matches a glob pattern")
+	removeCmd.Flags().BoolVarP(&removeDeleteBranch, "delete-branch", "D", false, "Also delete the branch after removing the worktree")
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
+	if removeMulti || removeFilter != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--multi and --filter cannot be used with a branch argument")
+		}
+		if removeMulti && removeFilter != "" {
+			return fmt.Errorf("--multi and --filter cannot be used together")
+		}
+		if removeFilter != "" {
+			return runRemoveFilter(removeFilter)
+		}
+		return runRemoveMulti()
+	}
+
 	var target string
 
 	if len(args) == 1 {
 		target = args[0]
+		resolved, err := resolveRemoveTarget(target)
+		if err != nil {
+			return err
+		}
+		target = resolved
 	} else {
 		selected, err := selector.SelectWorktree()
 		if err != nil {
-			if errors.Is(err, selector.ErrCancelled) {
-				return nil
-			}
 			return err
 		}
 		target = selected
 	}
 
-	fmt.Printf("Removing worktree '%s'...\n", target)
+	branch := target
+	if removeDeleteBranch {
+		// Before removing anything, so a detached worktree is kept
+		var err error
+		if branch, err = branchToDelete(target); err != nil {
+			return err
+		}
+	}
+
+	ok, err := confirmRemoval(target)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		output.Println("Aborted")
+		return nil
+	}
+
+	deleteBranch := removeDeleteBranch && confirmDeleteBranch(branch)
+
+	output.Printf("Removing worktree '%s'...\n", target)
+	removed, err := removeWorktree(target)
+	if err != nil {
+		return err
+	}
+
+	output.Printf("Worktree '%s' removed\n", target)
+	runOnRemove(removed)
+
+	if deleteBranch {
+		if err := worktree.DeleteBranch(branch, true); err != nil {
+			return err
+		}
+		output.Printf("Branch '%s' deleted\n", branch)
+	}
+	return nil
+}
+
+// branchToDelete returns the branch checked out in the worktree for target,
+// a branch or a path, for --delete-branch. A detached worktree has no branch
+// to delete and is refused.
+func branchToDelete(target string) (string, error) {
+	if _, err := worktree.FindByBranch(target); err == nil {
+		return target, nil
+	}
+
+	path, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	wt, err := worktree.FindByPath(path)
+	if err != nil {
+		return "", err
+	}
+	if wt.Branch == "(detached)" {
+		return "", fmt.Errorf("the worktree at %s has a detached HEAD, so there is no branch to delete\n\nRun again without --delete-branch", wt.Path)
+	}
+	return wt.Branch, nil
+}
+
+// resolveRemoveTarget returns target if it names a worktree branch or an
+// existing path, and otherwise the branch of a worktree resembling it (see
+// findWorktreeFuzzy).
+func resolveRemoveTarget(target string) (string, error) {
+	_, err := worktree.FindByBranch(target)
+	if err == nil {
+		return target, nil
+	}
+	if _, statErr := os.Stat(target); statErr == nil {
+		return target, nil
+	}
+
+	if prompt.AssumeYes() {
+		// --yes must not confirm removing a worktree that wasn't named
+		if _, matches, fuzzyErr := worktree.FindByBranchFuzzy(target); fuzzyErr == nil && len(matches) > 0 {
+			return "", exit.WithCode(exit.NotFound, fmt.Errorf("worktree for branch '%s' not found (did you mean '%s'?)\n\nClose matches aren't removed with --yes; name the branch exactly", target, strings.Join(matches, "', '")))
+		}
+		return "", err
+	}
+
+	wt, err := findWorktreeFuzzy(target, os.Stdout, err)
+	if err != nil {
+		return "", err
+	}
+	return wt.Branch, nil
+}
+
+// confirmRemoval shows the uncommitted changes and unpushed commits of the
+// worktree for target and reports whether to remove it. Without --force a
+// worktree with uncommitted changes is refused; with --force removal must be
+// confirmed unless --yes is set.
+func confirmRemoval(target string) (bool, error) {
+	wt, err := worktree.FindByBranch(target)
+	if err != nil {
+		// Not a branch name (e.g. a path); leave the checks to git
+		return true, nil
+	}
+
+	status, err := worktree.ShortStatus(wt.Path)
+	if err != nil {
+		return false, err
+	}
+	// What removing would lose is shown even with --quiet
+	if status != "" {
+		fmt.Printf("Worktree '%s' has uncommitted changes:\n", target)
+		for _, line := range strings.Split(status, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if wt.Branch != "(detached)" {
+		if n, err := worktree.UnpushedCommits(wt.Branch); err == nil && n > 0 {
+			fmt.Printf("Branch '%s' has %d commit(s) not pushed to any remote\n", wt.Branch, n)
+		}
+	}
+
+	if status != "" && !removeForce {
+		return false, fmt.Errorf("worktree '%s' has uncommitted changes; commit or stash them, or use --force to discard them", target)
+	}
+	if !removeForce {
+		return true, nil
+	}
+
+	if status != "" {
+		return prompt.Confirm(fmt.Sprintf("Remove worktree '%s' and discard its changes?", target)), nil
+	}
+	return prompt.Confirm(fmt.Sprintf("Force remove worktree '%s'?", target)), nil
+}
+
+// removeWorktree removes the worktree for target and forgets its pin.
+// Returns the removed worktree, or nil if target didn't name a branch.
+func removeWorktree(target string) (*worktree.Worktree, error) {
+	wt, findErr := worktree.FindByBranch(target)
+
 	if err := worktree.Remove(target, removeForce); err != nil {
+		return nil, err
+	}
+
+	if findErr != nil {
+		return nil, nil
+	}
+
+	// The worktree is gone either way; a stale pin is harmless
+	_ = state.SetPinned(wt.Path, false)
+	return wt, nil
+}
+
+// runOnRemove runs the configured on_remove commands for the removed wt
+// from the main worktree. The removal already succeeded, so failures are
+// only reported.
+func runOnRemove(wt *worktree.Worktree) {
+	if wt == nil {
+		return
+	}
+	if err := onRemove(wt); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: on_remove: %v\n", err)
+	}
+}
+
+func onRemove(wt *worktree.Worktree) error {
+	mainPath, err := worktree.GetMainWorktreePath()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadMerged(mainPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if len(cfg.OnRemove) == 0 {
+		return nil
+	}
+
+	repoName, _ := worktree.GetRepoName()
+	return hooks.RunOnRemove(mainPath, cfg.OnRemove, hooks.RemoveEvent{
+		Path:   wt.Path,
+		Branch: wt.Branch,
+		Repo:   repoName,
+	})
+}
+
+// checkBranchDeletion reports whether branch may be deleted and whether it is
+// merged into the default branch. Unmerged branches are kept unless --force is set.
+func checkBranchDeletion(branch string) (ok, merged bool) {
+	// Shown even with --quiet, since a branch asked to be deleted is kept
+	merged, err := worktree.IsMerged(branch)
+	if err != nil {
+		fmt.Printf("Cannot check whether '%s' is merged: %v\n", branch, err)
+		merged = false
+	}
+
+	if !merged && !removeForce {
+		fmt.Printf("Branch '%s' is not merged into the default branch; keeping it (use --force to delete)\n", branch)
+		return false, false
+	}
+	return true, merged
+}
+
+// confirmDeleteBranch asks whether to delete branch, showing its merge state.
+func confirmDeleteBranch(branch string) bool {
+	ok, merged := checkBranchDeletion(branch)
+	if !ok {
+		return false
+	}
+
+	state := "merged into the default branch"
+	if !merged {
+		state = "NOT merged into the default branch"
+	}
+	return prompt.Confirm(fmt.Sprintf("Delete branch '%s' (%s)?", branch, state))
+}
+
+func runRemoveMulti() error {
+	targets, err := selector.SelectWorktreesMulti()
+	if err != nil {
+		return err
+	}
+
+	return removeTargets(targets, confirmDeleteBranch)
+}
+
+func runRemoveFilter(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid filter %q: %w", pattern, err)
+	}
+
+	repo := worktree.NewRepo()
+	worktrees, err := repo.List()
+	if err != nil {
+		return err
+	}
+
+	mainPath, err := repo.MainWorktreePath()
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Worktree '%s' removed\n", target)
+	var targets []string
+	for _, wt := range worktrees {
+		if wt.Branch == "" || wt.Branch == "(detached)" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, wt.Branch); !ok {
+			continue
+		}
+		if wt.Path == mainPath {
+			output.Printf("Skipping '%s' (main worktree)\n", wt.Branch)
+			continue
+		}
+		if current, _ := worktree.IsCurrentDirInside(wt.Path); current {
+			output.Printf("Skipping '%s' (current worktree)\n", wt.Branch)
+			continue
+		}
+		targets = append(targets, wt.Branch)
+	}
+
+	if len(targets) == 0 {
+		output.Printf("No worktrees match '%s'\n", pattern)
+		return nil
+	}
+
+	// The worktrees to confirm are listed even with --quiet
+	fmt.Printf("The following %d worktree(s) match '%s':\n", len(targets), pattern)
+	for _, t := range targets {
+		fmt.Printf("  - %s\n", t)
+	}
+	question := "Remove them?"
+	if removeDeleteBranch {
+		question = "Remove them and delete their branches?"
+	}
+	if !prompt.Confirm(question) {
+		output.Println("Aborted")
+		return nil
+	}
+
+	// Confirmed once above; branch deletion only checks merge state
+	return removeTargets(targets, func(branch string) bool {
+		ok, _ := checkBranchDeletion(branch)
+		return ok
+	})
+}
+
+// removeTargets removes each worktree, reporting per-item success or failure.
+// With --delete-branch, allowDelete decides whether each branch is deleted.
+func removeTargets(targets []string, allowDelete func(branch string) bool) error {
+	var failed int
+	for _, target := range targets {
+		deleteBranch := removeDeleteBranch && allowDelete(target)
+
+		output.Printf("Removing worktree '%s'... ", target)
+		removed, err := removeWorktree(target)
+		if err != nil {
+			if output.IsQuiet() {
+				// Failures are always reported; restore the line prefix
+				fmt.Printf("Removing worktree '%s'... ", target)
+			}
+			fmt.Printf("failed: %v\n", err)
+			failed++
+			continue
+		}
+		output.Println("done")
+		runOnRemove(removed)
+
+		if deleteBranch {
+			if err := worktree.DeleteBranch(target, true); err != nil {
+				fmt.Printf("  %v\n", err)
+				failed++
+				continue
+			}
+			output.Printf("  deleted branch '%s'\n", target)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d of %d worktree(s)", failed, len(targets))
+	}
 	return nil
 }
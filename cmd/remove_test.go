@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBranchToDelete(t *testing.T) {
+	isolateGit(t)
+	dir := tempDir(t)
+	repo := filepath.Join(dir, "repo")
+	git(t, dir, "init", "--quiet", "--initial-branch=main", repo)
+	git(t, repo, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	git(t, repo, "worktree", "add", "--quiet", "-b", "feature", filepath.Join(dir, "feature"))
+	git(t, repo, "worktree", "add", "--quiet", "--detach", filepath.Join(dir, "detached"))
+	t.Chdir(repo)
+
+	tests := []struct {
+		target, want, wantErr string
+	}{
+		{target: "feature", want: "feature"},
+		{target: filepath.Join(dir, "feature"), want: "feature"},
+		{target: "../feature", want: "feature"},
+		{target: "../detached", wantErr: "detached HEAD"},
+		{target: "../missing", wantErr: "no worktree at"},
+	}
+	for _, tt := range tests {
+		got, err := branchToDelete(tt.target)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("branchToDelete(%s) = %q, %v; want an error containing %q", tt.target, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("branchToDelete(%s) = %q, %v; want %q", tt.target, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a branch and move its worktree",
+	Long: `Rename a branch and move its worktree to the matching standard location.
+
+This command:
+  1. Renames the branch using git branch -m
+  2. Moves the worktree to <repo>.worktrees/<new>
+
+The worktree cannot be moved while it is the current directory.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRename,
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	oldBranch, newBranch := args[0], args[1]
+
+	output.Printf("Renaming '%s' to '%s'...\n", oldBranch, newBranch)
+	newPath, err := worktree.Rename(oldBranch, newBranch)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Worktree '%s' is now at %s\n", newBranch, newPath)
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var reportJSON bool
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize the state of every worktree",
+	Long: `Print a summary of each worktree: its branch, age, last commit, how far it is
+ahead of or behind its upstream, whether it has uncommitted changes and whether
+its branch is merged into the default branch.
+
+The age of a linked worktree is the time since it was created; it is not known
+for the main worktree. Ahead/behind is only shown for branches with an upstream,
+and merged state is not shown for the main worktree or detached worktrees.
+
+Use --json for machine-readable output.`,
+	Args: cobra.NoArgs,
+	RunE: runReport,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().BoolVar(&reportJSON, "json", false, "Print the report as JSON")
+}
+
+// reportEntry is the summary of one worktree. Pointer fields are nil when
+// the value is unknown or doesn't apply.
+type reportEntry struct {
+	Branch     string     `json:"branch"`
+	Path       string     `json:"path"`
+	Created    *time.Time `json:"created,omitempty"`
+	LastCommit *time.Time `json:"last_commit,omitempty"`
+	Upstream   string     `json:"upstream,omitempty"`
+	Ahead      *int       `json:"ahead,omitempty"`
+	Behind     *int       `json:"behind,omitempty"`
+	Dirty      bool       `json:"dirty"`
+	Merged     *bool      `json:"merged,omitempty"`
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	worktrees, err := worktree.List()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]reportEntry, 0, len(worktrees))
+	for i, wt := range worktrees {
+		entries = append(entries, buildReportEntry(wt, i == 0))
+	}
+
+	if reportJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tAGE\tLAST COMMIT\tAHEAD/BEHIND\tDIRTY\tMERGED")
+	for _, e := range entries {
+		age := "-"
+		if e.Created != nil {
+			age = output.RelativeTime(*e.Created)
+		}
+		lastCommit := "-"
+		if e.LastCommit != nil {
+			lastCommit = output.RelativeTime(*e.LastCommit)
+		}
+		aheadBehind := "-"
+		if e.Ahead != nil && e.Behind != nil {
+			aheadBehind = fmt.Sprintf("+%d/-%d", *e.Ahead, *e.Behind)
+		}
+		dirty := "no"
+		if e.Dirty {
+			dirty = "yes"
+		}
+		merged := "-"
+		if e.Merged != nil {
+			merged = "no"
+			if *e.Merged {
+				merged = "yes"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Branch, age, lastCommit, aheadBehind, dirty, merged)
+	}
+	return w.Flush()
+}
+
+// buildReportEntry collects the report fields for wt. Fields that can't be
+// determined are left unset rather than failing the whole report.
+func buildReportEntry(wt worktree.Worktree, isMain bool) reportEntry {
+	e := reportEntry{Branch: wt.Branch, Path: wt.Path}
+
+	if created, err := worktree.CreatedAt(wt.Path); err == nil && !created.IsZero() {
+		e.Created = &created
+	}
+	if date, err := worktree.LastCommitDate(wt.Path); err == nil {
+		e.LastCommit = &date
+	}
+	if dirty, err := worktree.IsDirty(wt.Path); err == nil {
+		e.Dirty = dirty
+	}
+
+	if wt.Branch == "(detached)" {
+		return e
+	}
+
+	if upstream := worktree.GetUpstream(wt.Branch); upstream != "" {
+		e.Upstream = upstream
+		if ahead, behind, err := worktree.AheadBehind(wt.Branch, upstream); err == nil {
+			e.Ahead, e.Behind = &ahead, &behind
+		}
+	}
+
+	if !isMain {
+		if merged, err := worktree.IsMerged(wt.Branch); err == nil {
+			e.Merged = &merged
+		}
+	}
+
+	return e
+}
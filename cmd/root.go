@@ -2,16 +2,39 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"time"
 
+	"github.com/charmbracelet/x/term"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/debuglog"
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/hooks"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/updater"
 	"github.com/lucas-stellet/wk/internal/validate"
+	"github.com/lucas-stellet/wk/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
 var version = "dev"
 
+var (
+	quiet         bool
+	verbose       bool
+	configPath    string
+	noUpdateCheck bool
+	offline       bool
+	assumeYes     bool
+	debug         bool
+)
+
+// noUpdateCheckEnv disables the update check when set to anything but "",
+// "0" or "false".
+const noUpdateCheckEnv = "WK_NO_UPDATE_CHECK"
+
 // SetVersion sets the version string from main.
 func SetVersion(v string) {
 	version = v
@@ -26,49 +49,186 @@ It reads .wk.yaml from your project to automatically:
   - Copy files to new worktrees
   - Run post-creation hooks`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		switch {
+		case quiet:
+			output.SetLevel(output.Quiet)
+		case verbose:
+			output.SetLevel(output.Verbose)
+		}
+
+		prompt.SetAssumeYes(assumeYes)
+		updater.SetOffline(offline)
+		debuglog.Enable(debug)
+		debuglog.Printf("%s (version %s)", output.FormatCommand(os.Args), version)
+
+		if err := config.SetPath(configPath); err != nil {
+			return exit.WithCode(exit.InvalidConfig, err)
+		}
+
 		if err := validate.RunPreValidation(cmd); err != nil {
 			return err
 		}
 
+		applyConfig()
+
 		// Check for updates (skip for certain commands)
 		if shouldCheckUpdate(cmd) {
-			checkAndNotifyUpdate()
+			startUpdateCheck()
 		}
 
 		return nil
 	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		notifyUpdate()
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and essential results")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print more detail, including the git commands run")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Use this configuration file instead of searching for .wk.yaml")
+	rootCmd.PersistentFlags().BoolVar(&noUpdateCheck, "no-update-check", false, "Don't check for a new version of wk")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Don't use the network (also WK_OFFLINE=1)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to all confirmation prompts")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log the git commands run and their output to ~/.wk/debug.log (also WK_DEBUG=1)")
 }
 
 // Execute runs the root command.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	// Cobra adds the completion and help commands lazily; create them now so
+	// they can be annotated like the commands defined in this package.
+	rootCmd.InitDefaultCompletionCmd()
+	rootCmd.InitDefaultHelpCmd()
+	annotateCommand("completion", validate.SkipGitValidation, skipUpdateCheck)
+	annotateCommand("help", skipUpdateCheck)
+
+	// Errors and usage are printed below, where the exit code is known
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+
+	code := exit.CodeOf(err)
+	debuglog.Printf("error (exit %d): %v", code, err)
+	if code != exit.Cancelled {
+		cmd.PrintErrln(cmd.ErrPrefix(), err.Error())
+	}
+	// Usage only helps with generic errors such as a wrong flag or argument
+	if code == exit.Failure && (cmd == rootCmd || !cmd.SilenceUsage) {
+		cmd.Println(cmd.UsageString())
+	}
+	os.Exit(int(code))
+}
+
+// applyConfig loads the merged configuration, if any, and applies settings shared by all
+// commands. Errors are ignored here; validation already reported them.
+func applyConfig() {
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+
+	cfg, err := config.LoadMerged(wd)
+	if err != nil {
+		return
+	}
+
+	worktree.SetRemote(cfg.Remote)
+	worktree.SetPathTemplate(cfg.WorktreePathTemplate)
+	worktree.SetBranchDirStyle(string(cfg.BranchDirStyle))
+	hooks.SetShell(cfg.Shell)
+	hooks.SetReflink(cfg.CopyReflink == nil || *cfg.CopyReflink)
+}
+
+// skipUpdateCheck is the command annotation that opts a command (and its
+// subcommands) out of the update check, e.g. because its output is meant for
+// scripts:
+//
+//	Annotations: map[string]string{skipUpdateCheck: "true"}
+const skipUpdateCheck = "skip-update-check"
+
+// annotateCommand sets each of the annotations to "true" on the subcommand
+// of root named name, if there is one.
+func annotateCommand(name string, annotations ...string) {
+	c, _, err := rootCmd.Find([]string{name})
+	if err != nil || c.Name() != name {
+		return
+	}
+	if c.Annotations == nil {
+		c.Annotations = map[string]string{}
+	}
+	for _, a := range annotations {
+		c.Annotations[a] = "true"
 	}
 }
 
 // shouldCheckUpdate returns true if we should check for updates for this command.
+// The check is skipped with --no-update-check or WK_NO_UPDATE_CHECK, in
+// offline mode, when stdout or stderr isn't a terminal (e.g. in CI or when
+// output is captured), and for commands annotated with skipUpdateCheck.
 func shouldCheckUpdate(cmd *cobra.Command) bool {
-	name := cmd.Name()
-	// Skip update check for these commands
-	skipCommands := []string{"help", "version", "update", "completion"}
-	for _, skip := range skipCommands {
-		if name == skip {
+	if noUpdateCheck || updater.Offline() {
+		return false
+	}
+	switch os.Getenv(noUpdateCheckEnv) {
+	case "", "0", "false":
+	default:
+		return false
+	}
+	if !term.IsTerminal(os.Stdout.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		return false
+	}
+
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[skipUpdateCheck] == "true" {
 			return false
 		}
 	}
 	return true
 }
 
-// checkAndNotifyUpdate checks for updates using cache and notifies if available.
-func checkAndNotifyUpdate() {
-	// Run in background to not slow down command execution
-	info, err := updater.CachedCheck(version)
-	if err != nil {
-		// Silently ignore errors - don't interrupt user workflow
+// updateGrace is how long notifyUpdate waits for an update check still in
+// flight once the command is done, so a fresh check usually gets cached even
+// for quick commands.
+const updateGrace = 300 * time.Millisecond
+
+// updateResult receives the result of the update check started by
+// startUpdateCheck; nil if no check was started. It is buffered so the check
+// never blocks on a command that finished without reading it.
+var updateResult chan *updater.Info
+
+// startUpdateCheck checks for updates in the background, so a cache miss
+// doesn't delay the command. The result is reported by notifyUpdate.
+func startUpdateCheck() {
+	updateResult = make(chan *updater.Info, 1)
+	go func() {
+		info, err := updater.CachedCheck(version)
+		if err != nil {
+			// Silently ignore errors - don't interrupt user workflow
+			info = nil
+		}
+		updateResult <- info
+	}()
+}
+
+// notifyUpdate prints a hint after the command's output if the update check
+// found a new version. A check that hasn't finished within updateGrace is
+// abandoned; it ends with the process.
+func notifyUpdate() {
+	if updateResult == nil {
 		return
 	}
 
-	if info.UpdateAvailable {
-		fmt.Fprintf(os.Stderr, "hint: A new version of wk is available (%s). Run 'wk update' to upgrade.\n\n", info.LatestVersion)
+	var info *updater.Info
+	select {
+	case info = <-updateResult:
+	case <-time.After(updateGrace):
+	}
+
+	if info != nil && info.UpdateAvailable {
+		output.Hintf("A new version of wk is available (%s). Run 'wk update' to upgrade.\n", info.LatestVersion)
 	}
 }
@@ -9,21 +9,33 @@ import (
 
 	"github.com/lucas-stellet/wk/internal/config"
 	"github.com/lucas-stellet/wk/internal/hooks"
+	"github.com/lucas-stellet/wk/internal/output"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
-var setupQuiet bool
+var (
+	setupRerunOnce bool
+	setupNoHooks   bool
+	setupNoCopy    bool
+)
 
 var setupCmd = &cobra.Command{
 	Use:   "setup [path]",
 	Short: "Run copy and post hooks on an existing worktree",
-	Long: `Run the setup steps (file copy + post hooks) on an existing worktree.
+	Long: `Run the setup steps (file copy + post hooks) on an existing worktree,
+followed by any verify checks.
 
 If path is not specified, uses the current directory.
 
 This is useful for worktrees created externally (e.g. by Claude Code)
 that need the same setup that 'wk new' provides.
 
+Hooks marked 'once: true' are skipped if they already ran for the worktree;
+use --rerun-once to run them again.
+
+--no-copy skips copying and linking files, and --no-hooks skips post hooks
+and verify checks.
+
 Use -q/--quiet to suppress wk messages (hook output still shown).`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runSetup,
@@ -31,7 +43,9 @@ Use -q/--quiet to suppress wk messages (hook output still shown).`,
 
 func init() {
 	rootCmd.AddCommand(setupCmd)
-	setupCmd.Flags().BoolVarP(&setupQuiet, "quiet", "q", false, "Suppress wk messages (hook output still shown)")
+	setupCmd.Flags().BoolVar(&setupRerunOnce, "rerun-once", false, "Run hooks marked 'once' even if they already ran")
+	setupCmd.Flags().BoolVar(&setupNoHooks, "no-hooks", false, "Don't run post hooks or verify checks")
+	setupCmd.Flags().BoolVar(&setupNoCopy, "no-copy", false, "Don't copy or link files")
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
@@ -57,44 +71,70 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("get main worktree: %w", err)
 	}
 
-	// Load config from main worktree
-	configPath, err := config.FindConfig(srcDir)
+	// Load config from main worktree (global config merged with .wk.yaml)
+	cfg, err := config.LoadMerged(srcDir)
 	if os.IsNotExist(err) {
 		// No config found — exit silently (graceful degradation)
 		return nil
 	}
-	if err != nil {
-		return fmt.Errorf("find config: %w", err)
-	}
-
-	cfg, err := config.Load(configPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
+	printSkippedSteps(setupNoCopy, setupNoHooks)
+
 	// Copy files (skip if src == dst to avoid copying onto itself)
-	if srcDir != dstDir && len(cfg.Copy) > 0 {
-		if !setupQuiet {
-			fmt.Println("Copying files...")
+	if !setupNoCopy && srcDir != dstDir && (len(cfg.Copy) > 0 || cfg.CopyCommand != "") {
+		branch, err := worktree.GetBranchAt(dstDir)
+		if err != nil {
+			return fmt.Errorf("get branch: %w", err)
+		}
+		output.Println("Copying files...")
+		files, err := copyList(cmd.Context(), cfg, srcDir, hookEnv(branch, dstDir, srcDir))
+		if err != nil {
+			return err
 		}
-		if err := hooks.CopyFiles(srcDir, dstDir, cfg.Copy); err != nil {
+		if cfg.SkipTracked != nil && *cfg.SkipTracked {
+			files = skipTrackedFiles(srcDir, dstDir, files)
+		}
+		if err := hooks.CopyFiles(srcDir, dstDir, files, cfg.DirCopyMode); err != nil {
 			return fmt.Errorf("copy files: %w", err)
 		}
 	}
 
+	// Link files (same src == dst guard as copy)
+	if !setupNoCopy && srcDir != dstDir && len(cfg.Link) > 0 {
+		output.Println("Linking files...")
+		if err := hooks.LinkFiles(srcDir, dstDir, cfg.Link); err != nil {
+			return fmt.Errorf("link files: %w", err)
+		}
+	}
+
 	// Run post hooks
-	if len(cfg.PostHooks) > 0 {
-		if !setupQuiet {
-			fmt.Println("Running post hooks...")
+	if !setupNoHooks && len(cfg.PostHooks) > 0 {
+		output.Println("Running post hooks...")
+		branch, err := worktree.GetBranchAt(dstDir)
+		if err != nil {
+			return fmt.Errorf("get branch: %w", err)
 		}
-		if err := hooks.RunPostHooks(dstDir, cfg.PostHooks); err != nil {
+		if err := hooks.RunPostHooks(cmd.Context(), dstDir, cfg.PostHooks, hookEnv(branch, dstDir, srcDir), setupRerunOnce); err != nil {
 			return fmt.Errorf("run hooks: %w", err)
 		}
 	}
 
-	if !setupQuiet {
-		fmt.Println("Setup complete!")
+	// Run verify checks
+	if !setupNoHooks && len(cfg.Verify) > 0 {
+		output.Println("Verifying worktree...")
+		branch, err := worktree.GetBranchAt(dstDir)
+		if err != nil {
+			return fmt.Errorf("get branch: %w", err)
+		}
+		if err := hooks.RunVerify(cmd.Context(), dstDir, cfg.Verify, hookEnv(branch, dstDir, srcDir)); err != nil {
+			return fmt.Errorf("setup completed but worktree failed verification: %w", err)
+		}
 	}
 
+	output.Println("Setup complete!")
+
 	return nil
 }
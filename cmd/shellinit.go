@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/validate"
+)
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init [bash|zsh|fish]",
+	Short: "Print shell integration for 'wk switch'",
+	Long: `Print a shell function that makes 'wk switch' change the current shell's
+directory instead of spawning a subshell.
+
+If the shell is not specified, it is detected from $SHELL.
+
+Add one of the following to your shell configuration:
+
+  # bash (~/.bashrc) or zsh (~/.zshrc)
+  eval "$(wk shell-init)"
+
+  # fish (~/.config/fish/config.fish)
+  wk shell-init fish | source`,
+	Args:        cobra.MaximumNArgs(1),
+	ValidArgs:   []string{"bash", "zsh", "fish"},
+	RunE:        runShellInit,
+	Annotations: map[string]string{validate.SkipGitValidation: "true", skipUpdateCheck: "true"},
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+}
+
+// The wrappers run 'wk switch' with --print-path and cd to the path printed,
+// except with flags that print something else or don't switch this shell,
+// which are passed through unchanged.
+const posixShellInit = `wk() {
+  if [ "$1" = "switch" ]; then
+    shift
+    local arg
+    for arg in "$@"; do
+      case "$arg" in
+        -l|--list|--tmux|--tmux-session|-h|--help)
+          command wk switch "$@"
+          return $?
+          ;;
+      esac
+    done
+    local dir
+    dir="$(command wk switch --print-path "$@")" || return $?
+    [ -n "$dir" ] && cd "$dir"
+  else
+    command wk "$@"
+  fi
+}
+`
+
+const fishShellInit = `function wk
+  if test "$argv[1]" = switch
+    for arg in $argv[2..-1]
+      if contains -- $arg -l --list --tmux --tmux-session -h --help
+        command wk $argv
+        return $status
+      end
+    end
+    set -l dir (command wk switch --print-path $argv[2..-1]); or return $status
+    test -n "$dir"; and cd $dir
+  else
+    command wk $argv
+  end
+end
+`
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	var shell string
+	if len(args) == 1 {
+		shell = args[0]
+	} else {
+		shell = detectShell()
+	}
+
+	switch shell {
+	case "bash", "zsh":
+		fmt.Print(posixShellInit)
+	case "fish":
+		fmt.Print(fishShellInit)
+	case "":
+		return fmt.Errorf("could not detect shell from $SHELL\n\nSpecify one explicitly: wk shell-init [bash|zsh|fish]")
+	default:
+		return fmt.Errorf("unsupported shell %q (supported: bash, zsh, fish)", shell)
+	}
+
+	return nil
+}
+
+// detectShell returns the base name of $SHELL (e.g. "zsh").
+func detectShell() string {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		return ""
+	}
+	return filepath.Base(shell)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPosixShellInitArgs(t *testing.T) {
+	sh, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+
+	// A fake wk that logs its arguments and prints a path to switch to
+	bin, target := t.TempDir(), t.TempDir()
+	log := filepath.Join(bin, "args.log")
+	fake := "#!/bin/sh\necho \"$*\" >> " + log + "\necho " + target + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "wk"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		args, wantArgs string
+		cd             bool
+	}{
+		{"switch feat", "switch --print-path feat", true},
+		{"switch", "switch --print-path", true},
+		{"switch --list", "switch --list", false},
+		{"switch -l", "switch -l", false},
+		{"switch feat --tmux", "switch feat --tmux", false},
+		{"switch --tmux-session feat", "switch --tmux-session feat", false},
+		{"list", "list", false},
+	}
+	for _, tt := range tests {
+		os.Remove(log)
+		out, err := exec.Command(sh, "-c", posixShellInit+"wk "+tt.args+" >/dev/null; pwd").Output()
+		if err != nil {
+			t.Fatalf("wk %s: %v", tt.args, err)
+		}
+
+		data, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(data)); got != tt.wantArgs {
+			t.Errorf("wk %s ran wk %q, want %q", tt.args, got, tt.wantArgs)
+		}
+		if cd := strings.TrimSpace(string(out)) == target; cd != tt.cd {
+			t.Errorf("wk %s: changed directory = %v, want %v", tt.args, cd, tt.cd)
+		}
+	}
+}
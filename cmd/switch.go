@@ -1,16 +1,22 @@
 package cmd
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"runtime"
+	"text/tabwriter"
 	"time"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
 
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/selector"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
@@ -21,90 +27,330 @@ var switchCmd = &cobra.Command{
 	Long: `Switch to another worktree by opening a new shell in its directory.
 
 If branch is not specified, shows a list of available worktrees to choose from.
-If there are uncommitted changes, offers to stash them before switching.`,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runSwitch,
+--simple asks for the number of a worktree in a plain list instead; this is
+also used when stdin isn't a terminal or TERM is dumb.
+Branch may also be abbreviated to any prefix that matches a single worktree's
+branch; --list prints the shortest such prefix for each worktree. Failing
+that, offers the worktrees whose branch resembles it (e.g. feature/login for
+feat-login).
+If there are uncommitted changes, offers to stash them before switching. If
+wk stashed changes for the target branch earlier, offers to restore them.
+
+Use --print-path to print only the worktree path instead of opening a shell.
+This is used by the shell integration from 'wk shell-init'.
+
+Inside tmux, --tmux opens the worktree in a new window of the current session
+and --tmux-session in its own session, instead of a nested shell. Set
+'tmux: window' or 'tmux: session' in .wk.yaml to make this the default. Outside
+tmux these fall back to opening a shell.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeWorktreeBranches,
+	RunE:              runSwitch,
 }
 
+var (
+	switchList        bool
+	switchPrintPath   bool
+	switchTmux        bool
+	switchTmuxSession bool
+	switchSimple      bool
+)
+
 func init() {
 	rootCmd.AddCommand(switchCmd)
+	switchCmd.Flags().BoolVarP(&switchList, "list", "l", false, "List worktrees with their short branch prefixes")
+	switchCmd.Flags().BoolVar(&switchPrintPath, "print-path", false, "Print the worktree path instead of opening a shell")
+	switchCmd.Flags().BoolVar(&switchTmux, "tmux", false, "Open the worktree in a new tmux window")
+	switchCmd.Flags().BoolVar(&switchTmuxSession, "tmux-session", false, "Open the worktree in its own tmux session")
+	switchCmd.Flags().BoolVar(&switchSimple, "simple", false, "Choose the worktree from a numbered list instead of the interactive selector")
+	switchCmd.MarkFlagsMutuallyExclusive("tmux", "tmux-session")
+	switchCmd.MarkFlagsMutuallyExclusive("list", "simple")
+	switchCmd.MarkFlagsMutuallyExclusive("list", "print-path", "tmux", "tmux-session")
 }
 
 func runSwitch(cmd *cobra.Command, args []string) error {
 	var targetBranch string
 	var err error
 
+	if switchList {
+		if len(args) > 0 {
+			return fmt.Errorf("--list doesn't take a branch")
+		}
+		return listShortNames()
+	}
+
 	if len(args) == 1 {
 		targetBranch = args[0]
 	} else {
-		targetBranch, err = selector.SelectWorktree()
+		out := os.Stdout
+		if switchPrintPath {
+			// Keep stdout clean for the shell wrapper capturing it
+			out = os.Stderr
+		}
+		if switchSimple || !term.IsTerminal(os.Stdin.Fd()) || os.Getenv("TERM") == "dumb" {
+			targetBranch, err = selector.SelectWorktreeNumbered(out)
+		} else {
+			targetBranch, err = selector.SelectWorktreeTo(out)
+		}
 		if err != nil {
-			if errors.Is(err, selector.ErrCancelled) {
-				return nil
-			}
 			return err
 		}
 	}
 
-	wt, err := worktree.FindByBranch(targetBranch)
+	wt, err := findWorktree(targetBranch)
+	if err != nil && len(args) == 1 && exit.CodeOf(err) == exit.NotFound {
+		out := os.Stdout
+		if switchPrintPath {
+			out = os.Stderr
+		}
+		wt, err = findWorktreeFuzzy(targetBranch, out, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if switchPrintPath {
+		fmt.Println(wt.Path)
+		return nil
+	}
+
+	current, err := worktree.GetCurrentBranch()
 	if err != nil {
 		return err
 	}
 
-	if err := handleStashIfNeeded(); err != nil {
+	if err := handleStashIfNeeded(current); err != nil {
 		return err
 	}
 
-	fmt.Printf("Switching to worktree '%s' at %s\n", wt.Branch, wt.Path)
-	fmt.Println("Type 'exit' to return to the previous shell.")
-	return openShellAt(wt.Path)
+	if wt.Branch != current {
+		if err := handleStashRestore(wt); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	env, err := switchShellEnv(wt)
+	if err != nil {
+		return err
+	}
+
+	if mode := switchTmuxMode(); mode != "" {
+		if insideTmux() {
+			output.Printf("Opening worktree '%s' in a tmux %s\n", wt.Branch, mode)
+			return openTmuxAt(mode, wt.Path, wt.Branch, env)
+		}
+		fmt.Fprintln(os.Stderr, "Warning: not inside tmux; opening a shell instead")
+	}
+
+	output.Printf("Switching to worktree '%s' at %s\n", wt.Branch, wt.Path)
+	output.Println("Type 'exit' to return to the previous shell.")
+	return openShellAt(wt.Path, env)
 }
 
-func handleStashIfNeeded() error {
-	hasChanges, err := worktree.HasUncommittedChanges()
+// switchTmuxMode returns the tmux mode from --tmux or --tmux-session, falling
+// back to the config's tmux setting. Empty means don't use tmux.
+func switchTmuxMode() config.TmuxMode {
+	switch {
+	case switchTmux:
+		return config.TmuxWindow
+	case switchTmuxSession:
+		return config.TmuxSession
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	cfg, err := config.LoadMerged(wd)
+	if err != nil {
+		return ""
+	}
+	return cfg.Tmux
+}
+
+// listShortNames prints each worktree's branch with its shortest unique
+// prefix, which findWorktree accepts in place of the branch.
+func listShortNames() error {
+	repo := worktree.NewRepo()
+	worktrees, err := repo.List()
+	if err != nil {
+		return err
+	}
+	names, err := repo.AssignShortNames()
 	if err != nil {
 		return err
 	}
 
-	if !hasChanges {
-		return nil
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SHORT\tBRANCH\tPATH")
+	for _, wt := range worktrees {
+		short, ok := names[wt.Branch]
+		if !ok {
+			// Detached or bare; only reachable through the selector
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", short, wt.Branch, wt.Path)
 	}
+	return w.Flush()
+}
 
-	fmt.Print("You have uncommitted changes. Create stash before switching? [y/N]: ")
+// findWorktree returns the worktree for branch. If no worktree has that
+// branch, branch may be a prefix of a single worktree's branch, or a git
+// revision (e.g. "@{-1}", "HEAD~2") matched by the branch it names or by the
+// commit checked out.
+func findWorktree(branch string) (*worktree.Worktree, error) {
+	wt, err := worktree.FindByBranch(branch)
+	if err == nil {
+		return wt, nil
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToLower(input))
+	wt, prefixErr := worktree.FindByPrefix(branch)
+	if prefixErr == nil {
+		return wt, nil
+	}
 
-	if input != "y" && input != "yes" {
-		return nil
+	resolved, commit, resolveErr := worktree.ResolveRevision(branch)
+	if resolveErr != nil {
+		// Report an ambiguous prefix with its candidates
+		var ambiguous *worktree.AmbiguousPrefixError
+		if errors.As(prefixErr, &ambiguous) {
+			return nil, prefixErr
+		}
+		return nil, err
+	}
+	if resolved != "" {
+		return worktree.FindByBranch(resolved)
+	}
+	return worktree.FindByCommit(commit)
+}
+
+// findWorktreeFuzzy falls back to the worktrees whose branch resembles query
+// (see worktree.FindByBranchFuzzy) when no branch matches it. A single close
+// match is used once confirmed; several open the selector limited to them.
+// Questions and the selector are written to out. Returns notFound if nothing
+// resembles query or the match isn't confirmed.
+func findWorktreeFuzzy(query string, out io.Writer, notFound error) (*worktree.Worktree, error) {
+	wt, matches, err := worktree.FindByBranchFuzzy(query)
+	switch {
+	case err != nil:
+		return nil, notFound
+	case wt != nil:
+		return wt, nil
+	case len(matches) == 1:
+		if !prompt.ConfirmTo(out, fmt.Sprintf("No worktree for branch '%s'. Did you mean '%s'?", query, matches[0])) {
+			return nil, notFound
+		}
+		return worktree.FindByBranch(matches[0])
 	}
 
-	branch, err := worktree.GetCurrentBranch()
+	branch, err := selector.SelectWorktreeAmong(out, matches, fmt.Sprintf("Worktrees matching '%s'", query))
+	if err != nil {
+		return nil, err
+	}
+	return worktree.FindByBranch(branch)
+}
+
+// switchShellEnv returns the WK_* and shell_env variables for a shell in wt.
+func switchShellEnv(wt *worktree.Worktree) ([]string, error) {
+	mainPath, err := worktree.GetMainWorktreePath()
+	if err != nil {
+		return nil, err
+	}
+	env := hookEnv(wt.Branch, wt.Path, mainPath)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+	cfg, err := config.LoadMerged(wd)
+	if os.IsNotExist(err) {
+		return env.Vars(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	return env.ShellVars(cfg.ShellEnv)
+}
+
+func handleStashIfNeeded(branch string) error {
+	hasChanges, err := worktree.HasUncommittedChanges()
 	if err != nil {
 		return err
 	}
 
+	if !hasChanges {
+		return nil
+	}
+
+	if !prompt.Confirm("You have uncommitted changes. Create stash before switching?") {
+		return nil
+	}
+
 	stashName := generateStashName(branch)
-	fmt.Printf("Creating stash: %s\n", stashName)
+	output.Printf("Creating stash: %s\n", stashName)
 
 	return worktree.CreateStash(stashName)
 }
 
+// handleStashRestore offers to pop the latest stash wk created for wt's
+// branch into wt.
+func handleStashRestore(wt *worktree.Worktree) error {
+	ref, found, err := worktree.FindStashForBranch(wt.Branch)
+	if err != nil || !found {
+		return err
+	}
+
+	if !prompt.Confirm(fmt.Sprintf("Found changes stashed for '%s' (%s). Restore them?", wt.Branch, ref)) {
+		return nil
+	}
+
+	output.Printf("Restoring %s in %s\n", ref, wt.Path)
+	return worktree.PopStash(wt.Path, ref)
+}
+
+// defaultStashTimeFormat is the timestamp layout used when the config doesn't
+// set stash_time_format.
+const defaultStashTimeFormat = "150405-02012006"
+
 func generateStashName(branch string) string {
-	now := time.Now()
-	timestamp := now.Format("150405-02012006")
-	return fmt.Sprintf("%s-%s", branch, timestamp)
+	layout := defaultStashTimeFormat
+	if wd, err := os.Getwd(); err == nil {
+		if cfg, err := config.LoadMerged(wd); err == nil && cfg.StashTimeFormat != "" {
+			layout = cfg.StashTimeFormat
+		}
+	}
+	return worktree.StashMessage(branch, time.Now().Format(layout))
 }
 
-func openShellAt(dir string) error {
+// userShell returns the interactive shell to open in a worktree: $SHELL, or
+// bash if unset. On Windows, where $SHELL is usually unset (or, under Git
+// Bash, a path Windows can't run), it falls back to %COMSPEC% (cmd.exe) and
+// then PowerShell.
+func userShell() string {
 	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "bash"
+	if runtime.GOOS != "windows" {
+		if shell == "" {
+			return "bash"
+		}
+		return shell
 	}
 
-	cmd := exec.Command(shell)
+	if shell != "" {
+		if _, err := exec.LookPath(shell); err == nil {
+			return shell
+		}
+	}
+	if comspec := os.Getenv("COMSPEC"); comspec != "" {
+		return comspec
+	}
+	return "powershell"
+}
+
+func openShellAt(dir string, env []string) error {
+	cmd := exec.Command(userShell())
 	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
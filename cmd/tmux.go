@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/output"
+)
+
+// insideTmux reports whether wk runs inside a tmux client.
+func insideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// openTmuxAt opens dir in a new tmux window named name in the current
+// session, or in a new session named name that the client switches to, with
+// env set in its shell.
+func openTmuxAt(mode config.TmuxMode, dir, name string, env []string) error {
+	var args []string
+	switch mode {
+	case config.TmuxSession:
+		session := tmuxSessionName(name)
+		if exec.Command("tmux", "has-session", "-t", "="+session).Run() != nil {
+			args = []string{"new-session", "-d", "-s", session, "-c", dir}
+			args = append(args, tmuxEnvArgs(env)...)
+			if err := runTmux(args...); err != nil {
+				return err
+			}
+		}
+		return runTmux("switch-client", "-t", "="+session)
+	default:
+		args = []string{"new-window", "-c", dir, "-n", name}
+		args = append(args, tmuxEnvArgs(env)...)
+		return runTmux(args...)
+	}
+}
+
+// tmuxEnvArgs returns -e flags setting each VAR=value in env.
+func tmuxEnvArgs(env []string) []string {
+	var args []string
+	for _, kv := range env {
+		args = append(args, "-e", kv)
+	}
+	return args
+}
+
+// tmuxSessionName replaces characters tmux doesn't allow in session names.
+func tmuxSessionName(name string) string {
+	return strings.NewReplacer(".", "-", ":", "-").Replace(name)
+}
+
+func runTmux(args ...string) error {
+	cmd := exec.Command("tmux", args...)
+	output.Command(cmd)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tmux %s failed: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
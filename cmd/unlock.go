@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+var unlockCmd = &cobra.Command{
+	Use:               "unlock <branch>",
+	Short:             "Unlock a worktree locked with 'wk lock'",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorktreeBranches,
+	RunE:              runUnlock,
+}
+
+func init() {
+	rootCmd.AddCommand(unlockCmd)
+}
+
+func runUnlock(cmd *cobra.Command, args []string) error {
+	wt, err := findWorktree(args[0])
+	if err != nil {
+		return err
+	}
+	if !wt.Locked {
+		return fmt.Errorf("worktree '%s' is not locked", wt.Branch)
+	}
+
+	if err := worktree.Unlock(wt.Path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unlocked worktree '%s'\n", wt.Branch)
+	return nil
+}
@@ -1,17 +1,23 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
+	"github.com/lucas-stellet/wk/internal/prompt"
 	"github.com/lucas-stellet/wk/internal/updater"
+	"github.com/lucas-stellet/wk/internal/validate"
 	"github.com/spf13/cobra"
 )
 
 var (
-	forceUpdate bool
+	forceUpdate      bool
+	updateStatus     bool
+	updateClearCache bool
+	updateRollback   bool
+	updatePrerelease bool
+	updateStable     bool
 )
 
 var updateCmd = &cobra.Command{
@@ -20,16 +26,40 @@ var updateCmd = &cobra.Command{
 	Long: `Update wk to the latest version from GitHub releases.
 
 This command checks for updates and offers to download and install
-the latest version if one is available.`,
-	RunE: runUpdate,
+the latest version if one is available.
+
+Use --status to show the cached update check used by the update notifier,
+and --clear-cache to remove it so the next command checks again.
+
+Each update keeps the replaced binary in ~/.wk/backups (or
+$WK_STATE_DIR/backups); use --rollback to restore the most recent one.
+
+Use --prerelease to also consider release candidates and betas, or --stable to
+go back to full releases only. The choice is remembered, including by the
+update notifier.`,
+	RunE:        runUpdate,
+	Annotations: map[string]string{validate.SkipGitValidation: "true", skipUpdateCheck: "true"},
 }
 
 func init() {
 	updateCmd.Flags().BoolVarP(&forceUpdate, "force", "f", false, "Skip confirmation prompt")
+	updateCmd.Flags().BoolVar(&updateStatus, "status", false, "Show the cached update check")
+	updateCmd.Flags().BoolVar(&updateClearCache, "clear-cache", false, "Remove the cached update check")
+	updateCmd.Flags().BoolVar(&updateRollback, "rollback", false, "Restore the version installed before the last update")
+	updateCmd.Flags().BoolVar(&updatePrerelease, "prerelease", false, "Include pre-releases when checking for updates (remembered)")
+	updateCmd.Flags().BoolVar(&updateStable, "stable", false, "Only consider full releases when checking for updates (remembered)")
+	updateCmd.MarkFlagsMutuallyExclusive("prerelease", "stable")
 	rootCmd.AddCommand(updateCmd)
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
+	if updateClearCache {
+		return runClearUpdateCache()
+	}
+	if updateStatus {
+		return runUpdateStatus()
+	}
+
 	// Check install method first
 	method := updater.DetectInstallMethod()
 
@@ -44,9 +74,26 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Println("Checking for updates...")
+	if updateRollback {
+		return runRollback()
+	}
 
-	info, err := updater.CheckForUpdate(version)
+	channel, err := updateChannel()
+	if err != nil {
+		return err
+	}
+
+	if updater.Offline() {
+		return updater.ErrOffline
+	}
+
+	if channel == updater.ChannelPrerelease {
+		fmt.Println("Checking for updates (including pre-releases)...")
+	} else {
+		fmt.Println("Checking for updates...")
+	}
+
+	info, err := updater.CheckForUpdate(version, channel)
 	if err != nil {
 		return fmt.Errorf("failed to check for updates: %w", err)
 	}
@@ -67,12 +114,7 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	}
 
 	if !forceUpdate {
-		fmt.Print("Do you want to update? [y/N]: ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response != "y" && response != "yes" {
+		if !prompt.Confirm("Do you want to update?") {
 			fmt.Println("Update cancelled.")
 			return nil
 		}
@@ -87,3 +129,92 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	fmt.Printf("\nSuccessfully updated to %s\n", info.LatestVersion)
 	return nil
 }
+
+// updateChannel returns the update channel to use, persisting it first when
+// --prerelease or --stable is given.
+func updateChannel() (updater.Channel, error) {
+	var channel updater.Channel
+	switch {
+	case updatePrerelease:
+		channel = updater.ChannelPrerelease
+	case updateStable:
+		channel = updater.ChannelStable
+	default:
+		return updater.LoadChannel(), nil
+	}
+
+	if err := updater.SaveChannel(channel); err != nil {
+		return "", err
+	}
+	return channel, nil
+}
+
+func runUpdateStatus() error {
+	path, err := updater.CachePath()
+	if err != nil {
+		return err
+	}
+
+	cache, err := updater.LoadCache()
+	if os.IsNotExist(err) {
+		fmt.Println("No cached update check found.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read update cache: %w", err)
+	}
+
+	fmt.Printf("Cache file:       %s\n", path)
+	fmt.Printf("Channel:          %s\n", updater.LoadChannel())
+	fmt.Printf("Last checked:     %s (%s ago)\n", cache.CheckedAt.Format(time.RFC1123), time.Since(cache.CheckedAt).Round(time.Minute))
+	fmt.Printf("Checked version:  %s\n", cache.CurrentVersion)
+	fmt.Printf("Latest version:   %s\n", cache.LatestVersion)
+	fmt.Printf("Update available: %t\n", cache.UpdateAvailable)
+	if cache.CurrentVersion != version {
+		fmt.Printf("\nThe cache was written by a different version (running %s); it will be refreshed.\n", version)
+	}
+	return nil
+}
+
+func runClearUpdateCache() error {
+	err := updater.InvalidateCache()
+	if os.IsNotExist(err) {
+		fmt.Println("No cached update check to clear.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clear update cache: %w", err)
+	}
+
+	fmt.Println("Update cache cleared.")
+	return nil
+}
+
+func runRollback() error {
+	backup, err := updater.LatestBackup()
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	if backup == nil {
+		fmt.Println("No previous version available to roll back to.")
+		return nil
+	}
+
+	fmt.Printf("Current version:  %s\n", version)
+	fmt.Printf("Restore version:  %s\n", backup.Version)
+	fmt.Println()
+
+	if !forceUpdate {
+		if !prompt.Confirm("Do you want to roll back?") {
+			fmt.Println("Rollback cancelled.")
+			return nil
+		}
+	}
+
+	if err := updater.Rollback(); err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	fmt.Printf("\nSuccessfully rolled back to %s\n", backup.Version)
+	return nil
+}
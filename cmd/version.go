@@ -4,11 +4,14 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
+	"github.com/lucas-stellet/wk/internal/validate"
 )
 
 var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Show wk version",
+	Use:         "version",
+	Short:       "Show wk version",
+	Annotations: map[string]string{validate.SkipGitValidation: "true", skipUpdateCheck: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("wk version %s\n", version)
 	},
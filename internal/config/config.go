@@ -2,8 +2,15 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,12 +21,312 @@ const ConfigFileName = ".wk.yaml"
 // Config represents the wk configuration for a project.
 type Config struct {
 	// Copy lists files and directories to copy from source to new worktree.
-	Copy []string `yaml:"copy"`
+	Copy []CopyEntry `yaml:"copy"`
+	// CopyCommand is a shell command run in the source worktree whose output
+	// lists more paths to copy, one per line.
+	CopyCommand string `yaml:"copy_command,omitempty"`
+	// DirCopyMode controls how copied directories that already exist in the
+	// new worktree are handled. Defaults to merge.
+	DirCopyMode DirCopyMode `yaml:"dir_copy_mode,omitempty"`
+	// CopyReflink clones copied files with a copy-on-write reflink where the
+	// filesystem supports it, which is near-instant for large directories.
+	// Defaults to true.
+	CopyReflink *bool `yaml:"copy_reflink,omitempty"`
+	// Link lists files and directories to symlink from new worktree back to source.
+	Link []string `yaml:"link,omitempty"`
+	// SkipTracked skips copying files that are tracked by git and identical in
+	// the new worktree's committed tree, so they aren't clobbered by dirty copies.
+	// Defaults to false.
+	SkipTracked *bool `yaml:"skip_tracked,omitempty"`
 	// PostHooks lists commands to run after creating the worktree.
-	PostHooks []string `yaml:"post_hooks"`
+	PostHooks []Hook `yaml:"post_hooks"`
+	// PostCreateMain lists commands run in the main worktree once a new
+	// worktree is ready, e.g. to register it with an external tool. They
+	// get the same WK_* variables as post hooks.
+	PostCreateMain []Hook `yaml:"post_create_main,omitempty"`
+	// Verify lists checks run after the post hooks to confirm the worktree
+	// works, e.g. a build or health script. A failure is reported separately
+	// from setup failures.
+	Verify []Hook `yaml:"verify,omitempty"`
+	// RemoveOnVerifyFailure removes a worktree created by 'wk new' when a
+	// verify check fails, along with its branch if wk created it. Defaults
+	// to false.
+	RemoveOnVerifyFailure *bool `yaml:"remove_on_verify_failure,omitempty"`
+	// OnRemove lists commands run from the main worktree after a worktree is
+	// removed. Each receives a JSON event describing the removal on stdin.
+	OnRemove []string `yaml:"on_remove,omitempty"`
+	// Shell runs post_hooks, verify, copy_command and on_remove commands: a
+	// name looked up on PATH, like "bash", or a full path. Defaults to sh, or
+	// to cmd.exe (or PowerShell) on Windows.
+	Shell string `yaml:"shell,omitempty"`
+	// ShellEnv lists environment variables set in shells spawned by wk.
+	// Values are Go templates (e.g. "{{.Branch}}", "{{port 3000}}").
+	ShellEnv map[string]string `yaml:"shell_env,omitempty"`
+	// WorktreePathTemplate is a Go template for a worktree's path relative to
+	// the worktrees dir, e.g. "{{.Year}}/{{.Branch}}". Defaults to "{{.Branch}}".
+	WorktreePathTemplate string `yaml:"worktree_path_template,omitempty"`
+	// BranchDirStyle is how a branch name with slashes becomes a directory:
+	// nested (the default) keeps one directory per segment, flat replaces the
+	// slashes with dashes.
+	BranchDirStyle BranchDirStyle `yaml:"branch_dir_style,omitempty"`
+	// Tmux makes 'wk switch' open worktrees in a new tmux window or session
+	// instead of a subshell when run inside tmux. Empty means off.
+	Tmux TmuxMode `yaml:"tmux,omitempty"`
+	// Editor is the command 'wk open' runs with the worktree path, e.g.
+	// "code". Defaults to $VISUAL or $EDITOR.
+	Editor string `yaml:"editor,omitempty"`
+	// StashTimeFormat is the Go time layout of the timestamp in the names of
+	// stashes created by 'wk switch'. Defaults to "150405-02012006".
+	StashTimeFormat string `yaml:"stash_time_format,omitempty"`
+	// Remote is the git remote used for remote branches. Defaults to origin,
+	// or to the only remote when the repository has exactly one.
+	Remote string `yaml:"remote,omitempty"`
+	// DefaultBase is the revision 'wk new' creates new branches from, e.g.
+	// "develop", unless --from or --from-default is given. Defaults to HEAD.
+	DefaultBase string `yaml:"default_base,omitempty"`
 }
 
-// Load reads and parses a configuration file from the given path.
+// CopyEntry is a file or directory to copy to new worktrees. In YAML it is
+// either a path, copied to the same place, or an object naming where to put
+// it:
+//
+//	copy:
+//	  - .env
+//	  - from: env/template.env
+//	    to: .env.local
+type CopyEntry struct {
+	// From is the path in the source worktree.
+	From string `yaml:"from"`
+	// To is the path in the new worktree. Defaults to From.
+	To string `yaml:"to,omitempty"`
+}
+
+// Dest returns the path the entry is copied to.
+func (e CopyEntry) Dest() string {
+	if e.To == "" {
+		return e.From
+	}
+	return e.To
+}
+
+// String returns the entry as "from", or "from -> to" when renamed.
+func (e CopyEntry) String() string {
+	if e.Dest() == e.From {
+		return e.From
+	}
+	return e.From + " -> " + e.To
+}
+
+// copyEntryFields are the keys allowed in the object form of a copy entry.
+var copyEntryFields = map[string]bool{"from": true, "to": true}
+
+// UnmarshalYAML accepts either a path or the object form, and rejects
+// destinations outside the worktree.
+func (e *CopyEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*e = CopyEntry{From: value.Value}
+		return nil
+	}
+
+	if value.Kind == yaml.MappingNode {
+		var unknown []string
+		for i := 0; i < len(value.Content); i += 2 {
+			key := value.Content[i]
+			if !copyEntryFields[key.Value] {
+				unknown = append(unknown, fmt.Sprintf("line %d: unknown copy field %q", key.Line, key.Value))
+			}
+		}
+		if len(unknown) > 0 {
+			return &yaml.TypeError{Errors: unknown}
+		}
+	}
+
+	type plain CopyEntry
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	if p.From == "" {
+		return fmt.Errorf("line %d: copy entry must have a from path", value.Line)
+	}
+	if p.To != "" && !filepath.IsLocal(p.To) {
+		return fmt.Errorf("line %d: copy destination %q is outside the worktree", value.Line, p.To)
+	}
+	*e = CopyEntry(p)
+	return nil
+}
+
+// MarshalYAML writes entries copied to the same path as a plain path.
+func (e CopyEntry) MarshalYAML() (any, error) {
+	if e.Dest() == e.From {
+		return e.From, nil
+	}
+	type plain CopyEntry
+	return plain(e), nil
+}
+
+// DirCopyMode is how a copied directory is written over an existing one.
+type DirCopyMode string
+
+const (
+	// DirCopyMerge copies into the existing directory, overwriting files
+	// with the same name and leaving the others.
+	DirCopyMerge DirCopyMode = "merge"
+	// DirCopyReplace removes the existing directory before copying.
+	DirCopyReplace DirCopyMode = "replace"
+	// DirCopySkip leaves an existing directory untouched.
+	DirCopySkip DirCopyMode = "skip"
+)
+
+// UnmarshalYAML rejects unknown modes.
+func (m *DirCopyMode) UnmarshalYAML(value *yaml.Node) error {
+	switch mode := DirCopyMode(value.Value); mode {
+	case DirCopyMerge, DirCopyReplace, DirCopySkip:
+		*m = mode
+		return nil
+	}
+	return fmt.Errorf("line %d: dir_copy_mode must be merge, replace or skip, got %q", value.Line, value.Value)
+}
+
+// TmuxMode is where 'wk switch' opens a worktree when inside tmux.
+type TmuxMode string
+
+const (
+	// TmuxWindow opens a new window in the current session.
+	TmuxWindow TmuxMode = "window"
+	// TmuxSession opens a new session, or the existing one for the branch,
+	// and switches the client to it.
+	TmuxSession TmuxMode = "session"
+)
+
+// UnmarshalYAML rejects unknown modes.
+func (m *TmuxMode) UnmarshalYAML(value *yaml.Node) error {
+	switch mode := TmuxMode(value.Value); mode {
+	case TmuxWindow, TmuxSession:
+		*m = mode
+		return nil
+	}
+	return fmt.Errorf("line %d: tmux must be window or session, got %q", value.Line, value.Value)
+}
+
+// BranchDirStyle is how a branch name is turned into a worktree directory.
+type BranchDirStyle string
+
+const (
+	// BranchDirNested keeps slashes, so "feature/login" is created at
+	// feature/login.
+	BranchDirNested BranchDirStyle = "nested"
+	// BranchDirFlat replaces slashes with dashes, so "feature/login" is
+	// created at feature-login.
+	BranchDirFlat BranchDirStyle = "flat"
+)
+
+// UnmarshalYAML rejects unknown styles.
+func (s *BranchDirStyle) UnmarshalYAML(value *yaml.Node) error {
+	switch style := BranchDirStyle(value.Value); style {
+	case BranchDirNested, BranchDirFlat:
+		*s = style
+		return nil
+	}
+	return fmt.Errorf("line %d: branch_dir_style must be nested or flat, got %q", value.Line, value.Value)
+}
+
+// Hook is a command run after creating a worktree. In YAML it is either a
+// plain command string or an object:
+//
+//   - npm install
+//   - name: install frontend deps
+//     run: npm install
+//     dir: frontend
+//   - run: make migrate
+//     when: feature/*
+type Hook struct {
+	// Name is an optional label printed before the hook runs.
+	Name string `yaml:"name,omitempty"`
+	// Run is the shell command to execute.
+	Run string `yaml:"run"`
+	// Dir is the directory to run in, relative to the worktree root.
+	Dir string `yaml:"dir,omitempty"`
+	// Once runs the hook only the first time a worktree is set up.
+	Once bool `yaml:"once,omitempty"`
+	// When limits the hook to branches matching a glob (e.g. "feature/*"),
+	// or a regular expression when enclosed in slashes (e.g. "/^fix-\d+$/").
+	When string `yaml:"when,omitempty"`
+}
+
+// hookFields are the keys allowed in the object form of a hook.
+var hookFields = map[string]bool{"name": true, "run": true, "dir": true, "once": true, "when": true}
+
+// UnmarshalYAML accepts either a command string or the object form.
+func (h *Hook) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*h = Hook{Run: value.Value}
+		return nil
+	}
+
+	// Node.Decode doesn't inherit the decoder's KnownFields, so check keys
+	// here. A TypeError lets decoding continue and report other errors too.
+	if value.Kind == yaml.MappingNode {
+		var unknown []string
+		for i := 0; i < len(value.Content); i += 2 {
+			key := value.Content[i]
+			if !hookFields[key.Value] {
+				unknown = append(unknown, fmt.Sprintf("line %d: unknown hook field %q", key.Line, key.Value))
+			}
+		}
+		if len(unknown) > 0 {
+			return &yaml.TypeError{Errors: unknown}
+		}
+	}
+
+	type plain Hook
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return err
+	}
+	if p.Run == "" {
+		return fmt.Errorf("line %d: hook must have a run command", value.Line)
+	}
+	if _, err := Hook(p).Matches(""); err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*h = Hook(p)
+	return nil
+}
+
+// Matches reports whether the hook applies to branch: always when When is
+// empty, otherwise when branch matches its glob or /regexp/.
+func (h Hook) Matches(branch string) (bool, error) {
+	if h.When == "" {
+		return true, nil
+	}
+
+	if len(h.When) > 1 && strings.HasPrefix(h.When, "/") && strings.HasSuffix(h.When, "/") {
+		re, err := regexp.Compile(h.When[1 : len(h.When)-1])
+		if err != nil {
+			return false, fmt.Errorf("invalid when regexp %q: %w", h.When, err)
+		}
+		return re.MatchString(branch), nil
+	}
+
+	ok, err := path.Match(h.When, branch)
+	if err != nil {
+		return false, fmt.Errorf("invalid when pattern %q: %w", h.When, err)
+	}
+	return ok, nil
+}
+
+// MarshalYAML writes hooks with only a command as a plain string.
+func (h Hook) MarshalYAML() (interface{}, error) {
+	if h.Name == "" && h.Dir == "" && !h.Once && h.When == "" {
+		return h.Run, nil
+	}
+	type plain Hook
+	return plain(h), nil
+}
+
+// Load reads and parses a configuration file from the given path. Unknown
+// fields, such as a misspelled key, are errors naming the field and line.
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -27,15 +334,40 @@ func Load(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, describeYAMLError(err)
 	}
 
 	return &cfg, nil
 }
 
+// unknownFieldRe matches yaml.v3's message for a key with no struct field.
+var unknownFieldRe = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+// describeYAMLError rewords yaml.v3 decoding errors, one per line, e.g.
+// "line 3: unknown field \"post_hook\"".
+func describeYAMLError(err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+
+	msgs := make([]string, len(typeErr.Errors))
+	for i, msg := range typeErr.Errors {
+		msgs[i] = unknownFieldRe.ReplaceAllString(msg, `unknown field "$1"`)
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
 // FindConfig searches for .wk.yaml starting from dir and walking up to root.
+// If a file was set with SetPath, returns it instead.
 func FindConfig(dir string) (string, error) {
+	if explicitPath != "" {
+		return explicitPath, nil
+	}
+
 	dir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", err
@@ -56,3 +388,283 @@ func FindConfig(dir string) (string, error) {
 
 	return "", os.ErrNotExist
 }
+
+// explicitPath is the configuration file set with SetPath.
+var explicitPath string
+
+// SetPath makes FindConfig return path instead of searching for
+// ConfigFileName, as set by the global --config flag. A relative path is
+// resolved against the working directory; empty restores the search.
+func SetPath(path string) error {
+	if path == "" {
+		explicitPath = ""
+		return nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("config file %s does not exist", path)
+		}
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	explicitPath = abs
+	return nil
+}
+
+// GlobalConfigPath returns the path of the global configuration file:
+// $XDG_CONFIG_HOME/wk/config.yaml, falling back to ~/.config/wk/config.yaml.
+func GlobalConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "wk", "config.yaml"), nil
+}
+
+// LoadMerged loads the global configuration and overlays the .wk.yaml found
+// from repoDir on top of it (see Merge). Returns os.ErrNotExist if neither
+// file exists.
+func LoadMerged(repoDir string) (*Config, error) {
+	res, err := Resolve(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	return res.Config, nil
+}
+
+// Resolution is a merged configuration together with the files it was
+// loaded from and the file each entry came from.
+type Resolution struct {
+	Config *Config
+	// Files lists the configuration files that were loaded, global first.
+	Files   []string
+	origins map[string]string
+}
+
+// Origin returns the file that contributed value to field, where field is
+// the YAML key (e.g. "copy", "post_hooks", "shell_env") and value is the
+// entry, hook command or map key. Returns "" if the entry is unknown.
+func (r *Resolution) Origin(field, value string) string {
+	return r.origins[field+":"+value]
+}
+
+// Resolve is like LoadMerged but also records where each entry came from.
+func Resolve(repoDir string) (*Resolution, error) {
+	var global, repo *Config
+	res := &Resolution{origins: make(map[string]string)}
+
+	if path, err := GlobalConfigPath(); err == nil {
+		cfg, err := Load(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("global config %s: %w", path, err)
+		}
+		if cfg != nil {
+			global = cfg
+			res.record(cfg, path)
+		}
+	}
+
+	repoPath, err := FindConfig(repoDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		repo, err = Load(repoPath)
+		if err != nil {
+			return nil, err
+		}
+		res.record(repo, repoPath)
+	}
+
+	switch {
+	case global == nil && repo == nil:
+		return nil, os.ErrNotExist
+	case global == nil:
+		res.Config = repo
+	case repo == nil:
+		res.Config = global
+	default:
+		res.Config = Merge(global, repo)
+	}
+	return res, nil
+}
+
+// record notes path as the origin of the entries in cfg, following the
+// precedence of Merge: list entries keep their first origin, while map
+// entries and scalars take the last.
+func (r *Resolution) record(cfg *Config, path string) {
+	r.Files = append(r.Files, path)
+
+	first := func(key string) {
+		if _, ok := r.origins[key]; !ok {
+			r.origins[key] = path
+		}
+	}
+	for _, f := range cfg.Copy {
+		first("copy:" + f.String())
+	}
+	for _, f := range cfg.Link {
+		first("link:" + f)
+	}
+	for _, h := range cfg.PostHooks {
+		first("post_hooks:" + h.Run)
+	}
+	for _, h := range cfg.PostCreateMain {
+		first("post_create_main:" + h.Run)
+	}
+	for _, h := range cfg.Verify {
+		first("verify:" + h.Run)
+	}
+	for _, c := range cfg.OnRemove {
+		first("on_remove:" + c)
+	}
+	for k := range cfg.ShellEnv {
+		r.origins["shell_env:"+k] = path
+	}
+	if cfg.CopyCommand != "" {
+		r.origins["copy_command:"+cfg.CopyCommand] = path
+	}
+	if cfg.DirCopyMode != "" {
+		r.origins["dir_copy_mode:"+string(cfg.DirCopyMode)] = path
+	}
+	if cfg.CopyReflink != nil {
+		r.origins[fmt.Sprintf("copy_reflink:%t", *cfg.CopyReflink)] = path
+	}
+	if cfg.SkipTracked != nil {
+		r.origins[fmt.Sprintf("skip_tracked:%t", *cfg.SkipTracked)] = path
+	}
+	if cfg.RemoveOnVerifyFailure != nil {
+		r.origins[fmt.Sprintf("remove_on_verify_failure:%t", *cfg.RemoveOnVerifyFailure)] = path
+	}
+	if cfg.Tmux != "" {
+		r.origins["tmux:"+string(cfg.Tmux)] = path
+	}
+	if cfg.Editor != "" {
+		r.origins["editor:"+cfg.Editor] = path
+	}
+	if cfg.StashTimeFormat != "" {
+		r.origins["stash_time_format:"+cfg.StashTimeFormat] = path
+	}
+	if cfg.Remote != "" {
+		r.origins["remote:"+cfg.Remote] = path
+	}
+	if cfg.DefaultBase != "" {
+		r.origins["default_base:"+cfg.DefaultBase] = path
+	}
+	if cfg.Shell != "" {
+		r.origins["shell:"+cfg.Shell] = path
+	}
+	if cfg.WorktreePathTemplate != "" {
+		r.origins["worktree_path_template:"+cfg.WorktreePathTemplate] = path
+	}
+	if cfg.BranchDirStyle != "" {
+		r.origins["branch_dir_style:"+string(cfg.BranchDirStyle)] = path
+	}
+}
+
+// Merge returns global overlaid with repo. List fields are appended (global
+// entries first, duplicates removed, so a hook listed in both files runs
+// once). Map entries and scalar fields, booleans included, set in repo
+// override global.
+func Merge(global, repo *Config) *Config {
+	merged := &Config{
+		Copy:                  appendUnique(global.Copy, repo.Copy),
+		CopyCommand:           global.CopyCommand,
+		DirCopyMode:           global.DirCopyMode,
+		CopyReflink:           global.CopyReflink,
+		Link:                  appendUnique(global.Link, repo.Link),
+		SkipTracked:           global.SkipTracked,
+		PostHooks:             appendUnique(global.PostHooks, repo.PostHooks),
+		PostCreateMain:        appendUnique(global.PostCreateMain, repo.PostCreateMain),
+		Verify:                appendUnique(global.Verify, repo.Verify),
+		RemoveOnVerifyFailure: global.RemoveOnVerifyFailure,
+		OnRemove:              appendUnique(global.OnRemove, repo.OnRemove),
+		Shell:                 global.Shell,
+		ShellEnv:              mergeMaps(global.ShellEnv, repo.ShellEnv),
+		Tmux:                  global.Tmux,
+		Editor:                global.Editor,
+		StashTimeFormat:       global.StashTimeFormat,
+		Remote:                global.Remote,
+		DefaultBase:           global.DefaultBase,
+		WorktreePathTemplate:  global.WorktreePathTemplate,
+		BranchDirStyle:        global.BranchDirStyle,
+	}
+	if repo.CopyCommand != "" {
+		merged.CopyCommand = repo.CopyCommand
+	}
+	if repo.DirCopyMode != "" {
+		merged.DirCopyMode = repo.DirCopyMode
+	}
+	if repo.CopyReflink != nil {
+		merged.CopyReflink = repo.CopyReflink
+	}
+	if repo.SkipTracked != nil {
+		merged.SkipTracked = repo.SkipTracked
+	}
+	if repo.RemoveOnVerifyFailure != nil {
+		merged.RemoveOnVerifyFailure = repo.RemoveOnVerifyFailure
+	}
+	if repo.Tmux != "" {
+		merged.Tmux = repo.Tmux
+	}
+	if repo.Editor != "" {
+		merged.Editor = repo.Editor
+	}
+	if repo.StashTimeFormat != "" {
+		merged.StashTimeFormat = repo.StashTimeFormat
+	}
+	if repo.Remote != "" {
+		merged.Remote = repo.Remote
+	}
+	if repo.DefaultBase != "" {
+		merged.DefaultBase = repo.DefaultBase
+	}
+	if repo.Shell != "" {
+		merged.Shell = repo.Shell
+	}
+	if repo.WorktreePathTemplate != "" {
+		merged.WorktreePathTemplate = repo.WorktreePathTemplate
+	}
+	if repo.BranchDirStyle != "" {
+		merged.BranchDirStyle = repo.BranchDirStyle
+	}
+	return merged
+}
+
+// mergeMaps returns a map with the entries of a overridden by those of b.
+func mergeMaps(a, b map[string]string) map[string]string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		result[k] = v
+	}
+	for k, v := range b {
+		result[k] = v
+	}
+	return result
+}
+
+// appendUnique returns a followed by the entries of b not already present.
+func appendUnique[T comparable](a, b []T) []T {
+	seen := make(map[T]bool, len(a)+len(b))
+	var result []T
+	for _, list := range [][]T{a, b} {
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}
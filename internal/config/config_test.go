@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes content to a .wk.yaml in a temporary directory and
+// returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "misspelled key",
+			content: "copy:\n  - .env\npost_hook:\n  - npm ci\n",
+			want:    `line 3: unknown field "post_hook"`,
+		},
+		{
+			name:    "wrong type",
+			content: "skip_tracked: [yes]\n",
+			want:    "line 1: cannot unmarshal !!seq into bool",
+		},
+		{
+			name:    "unknown hook field",
+			content: "post_hooks:\n  - run: npm ci\n    onse: true\n",
+			want:    `line 3: unknown hook field "onse"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValidConfig(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "copy:\n  - .env\npost_hooks:\n  - npm ci\n  - run: make\n    once: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Copy) != 1 || cfg.Copy[0].From != ".env" {
+		t.Errorf("Copy = %v, want [.env]", cfg.Copy)
+	}
+	if len(cfg.PostHooks) != 2 || !cfg.PostHooks[1].Once {
+		t.Errorf("PostHooks = %v, want npm ci and a once hook", cfg.PostHooks)
+	}
+}
+
+func TestMergeBooleans(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name         string
+		global, repo *bool
+		want         *bool
+	}{
+		{"unset", nil, nil, nil},
+		{"global only", &yes, nil, &yes},
+		{"repo only", nil, &yes, &yes},
+		{"repo turns off", &yes, &no, &no},
+		{"repo turns on", &no, &yes, &yes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := Merge(
+				&Config{SkipTracked: tt.global, RemoveOnVerifyFailure: tt.global},
+				&Config{SkipTracked: tt.repo, RemoveOnVerifyFailure: tt.repo},
+			)
+			for field, got := range map[string]*bool{
+				"skip_tracked":             merged.SkipTracked,
+				"remove_on_verify_failure": merged.RemoveOnVerifyFailure,
+			} {
+				if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+					t.Errorf("%s = %v, want %v", field, fmtBool(got), fmtBool(tt.want))
+				}
+			}
+		})
+	}
+}
+
+// fmtBool formats an optional boolean for test failures.
+func fmtBool(b *bool) string {
+	if b == nil {
+		return "unset"
+	}
+	return strconv.FormatBool(*b)
+}
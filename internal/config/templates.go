@@ -0,0 +1,33 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed templates/*.yaml
+var templateFS embed.FS
+
+// TemplateNames returns the names of the built-in starter configurations
+// for 'wk init --template', sorted.
+func TemplateNames() []string {
+	entries, _ := templateFS.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template returns the contents of the built-in starter configuration name.
+func Template(name string) ([]byte, error) {
+	data, err := templateFS.ReadFile(path.Join("templates", name+".yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(TemplateNames(), ", "))
+	}
+	return data, nil
+}
@@ -0,0 +1,157 @@
+// Package debuglog records the git commands wk runs, with their exit status
+// and output, in a file users can attach to bug reports. It is off unless
+// turned on with the global --debug flag or WK_DEBUG.
+package debuglog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/state"
+)
+
+// Env turns the debug log on when set to anything but "", "0" or "false".
+const Env = "WK_DEBUG"
+
+const (
+	fileName = "debug.log"
+	// maxSize is the size past which the log is moved to debug.log.1,
+	// replacing the previous one, and a new log is started.
+	maxSize = 1 << 20
+	// maxOutput is how much of each command's stdout and stderr is logged.
+	maxOutput = 2048
+)
+
+var (
+	enabled bool
+	mu      sync.Mutex
+	file    *os.File
+	// opened records that opening the file was attempted, so a failure is
+	// reported once.
+	opened bool
+)
+
+// Enable turns the debug log on, in addition to Env.
+func Enable(on bool) {
+	enabled = on
+}
+
+// Enabled reports whether the debug log is on.
+func Enabled() bool {
+	if enabled {
+		return true
+	}
+	switch os.Getenv(Env) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+// Path returns the path of the debug log, in the state directory.
+func Path() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Printf adds a timestamped entry to the debug log, if it is on.
+func Printf(format string, a ...any) {
+	if !Enabled() {
+		return
+	}
+	write(fmt.Sprintf(format, a...) + "\n")
+}
+
+// Command logs cmd after it ran, with its exit status from err, how long it
+// took and its output, truncated.
+func Command(cmd *exec.Cmd, took time.Duration, err error, stdout, stderr []byte) {
+	if !Enabled() {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(output.FormatCommand(cmd.Args))
+	if cmd.Dir != "" {
+		fmt.Fprintf(&b, "  (in %s)", cmd.Dir)
+	}
+	fmt.Fprintf(&b, "\n  exit: %s, took %s\n", exitStatus(err), took.Round(time.Millisecond))
+	writeOutput(&b, "stdout", stdout)
+	writeOutput(&b, "stderr", stderr)
+	write(b.String())
+}
+
+// exitStatus describes how a command that returned err exited.
+func exitStatus(err error) string {
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return "0"
+	case errors.As(err, &exitErr):
+		return fmt.Sprint(exitErr.ExitCode())
+	}
+	return err.Error()
+}
+
+// writeOutput adds the output of stream to b, indented and truncated to
+// maxOutput bytes.
+func writeOutput(b *strings.Builder, stream string, data []byte) {
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return
+	}
+	var truncated string
+	if len(text) > maxOutput {
+		truncated = fmt.Sprintf("\n    ... (%d bytes truncated)", len(text)-maxOutput)
+		text = text[:maxOutput]
+	}
+	fmt.Fprintf(b, "  %s:\n    %s%s\n", stream, strings.ReplaceAll(text, "\n", "\n    "), truncated)
+}
+
+// write appends entry to the log with a timestamp, opening the log first if
+// needed. Errors are reported once on stderr; the log must never make a
+// command fail.
+func write(entry string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !opened {
+		opened = true
+		var err error
+		file, err = open()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: debug log: %v\n", err)
+		}
+	}
+	if file == nil {
+		return
+	}
+	fmt.Fprintf(file, "%s %s", time.Now().Format("2006-01-02T15:04:05.000Z07:00"), entry)
+}
+
+// open opens the log for appending, starting a new one if it has grown past
+// maxSize.
+func open() (*os.File, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > maxSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
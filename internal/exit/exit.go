@@ -0,0 +1,60 @@
+// Package exit defines the exit codes wk returns, so scripts can tell
+// failures apart, and an error type that carries one.
+package exit
+
+import "errors"
+
+// Code is a process exit code.
+type Code int
+
+const (
+	// OK means the command succeeded.
+	OK Code = 0
+	// Failure is returned for errors without a more specific code.
+	Failure Code = 1
+	// NotGitRepository means wk was run outside a git repository or git
+	// could not be found.
+	NotGitRepository Code = 2
+	// InvalidConfig means the configuration file is missing or invalid.
+	InvalidConfig Code = 3
+	// NotFound means a branch or worktree does not exist.
+	NotFound Code = 4
+	// Cancelled means the user cancelled a selection or interrupted a
+	// command.
+	Cancelled Code = 5
+)
+
+// Error is an error that makes wk exit with Code.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCode wraps err so that wk exits with code. Returns nil if err is nil.
+func WithCode(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf returns the exit code for err: OK for nil, the code of the first
+// *Error in its chain, or Failure.
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Failure
+}
@@ -2,22 +2,41 @@
 package hooks
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/output"
 )
 
-// CopyFiles copies files and directories from src to dst.
-func CopyFiles(src, dst string, files []string) error {
-	for _, file := range files {
-		srcPath := filepath.Join(src, file)
-		dstPath := filepath.Join(dst, file)
+// CopyFiles copies files and directories from src to dst, each to the
+// destination its entry names. mode decides what happens to directories that
+// already exist in dst; empty means merge. In verbose mode, copying a large
+// directory reports progress and the number of files and bytes copied.
+func CopyFiles(src, dst string, files []config.CopyEntry, mode config.DirCopyMode) error {
+	for _, entry := range files {
+		if err := checkCopyPath(entry.From); err != nil {
+			return err
+		}
+		if err := checkCopyPath(entry.Dest()); err != nil {
+			return err
+		}
+		file := entry.String()
+		srcPath := filepath.Join(src, entry.From)
+		dstPath := filepath.Join(dst, entry.Dest())
 
 		info, err := os.Stat(srcPath)
 		if os.IsNotExist(err) {
-			fmt.Printf("  skipping %s (not found)\n", file)
+			output.Printf("  skipping %s (not found)\n", file)
 			continue
 		}
 		if err != nil {
@@ -25,49 +44,163 @@ func CopyFiles(src, dst string, files []string) error {
 		}
 
 		if info.IsDir() {
-			if err := copyDir(srcPath, dstPath); err != nil {
+			if _, err := os.Lstat(dstPath); err == nil {
+				switch mode {
+				case config.DirCopySkip:
+					output.Printf("  skipping %s (already exists)\n", file)
+					continue
+				case config.DirCopyReplace:
+					if err := os.RemoveAll(dstPath); err != nil {
+						return fmt.Errorf("replace directory %s: %w", file, err)
+					}
+				}
+			}
+			var progress *copyProgress
+			if output.IsVerbose() {
+				progress = newCopyProgress(file, srcPath)
+			}
+			err := copyDir(srcPath, dstPath, progress)
+			progress.clear()
+			if err != nil {
 				return fmt.Errorf("copy directory %s: %w", file, err)
 			}
+			if progress != nil {
+				output.Printf("  copied %s (%s)\n", file, progress.summary())
+				continue
+			}
 		} else {
 			if err := copyFile(srcPath, dstPath); err != nil {
 				return fmt.Errorf("copy file %s: %w", file, err)
 			}
 		}
-		fmt.Printf("  copied %s\n", file)
+		output.Printf("  copied %s\n", file)
+	}
+	return nil
+}
+
+// CopyCommandFiles runs command in src and returns the paths it prints, one
+// per line, for copying alongside the static copy list. Blank lines are
+// ignored; paths must be relative and stay inside the worktree. The command
+// is killed if ctx is done first.
+func CopyCommandFiles(ctx context.Context, src, command string, env Env) ([]string, error) {
+	output.Printf("  running: %s\n", command)
+
+	var stdout bytes.Buffer
+	cmd, err := shellCommand(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), env.Vars()...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("copy_command %q failed: %w", command, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		file := strings.TrimSpace(line)
+		if file == "" {
+			continue
+		}
+		if err := checkCopyPath(file); err != nil {
+			return nil, fmt.Errorf("copy_command %q: %w", command, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// checkCopyPath rejects copy entries that are absolute or escape the
+// worktree, so copying can't write outside the new worktree.
+func checkCopyPath(file string) error {
+	rel := filepath.Clean(file)
+	if filepath.IsAbs(file) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("copy path %q is outside the worktree", file)
 	}
 	return nil
 }
 
+// copyFile copies the contents of src to dst, preserving its mode and
+// modification time. Symlinks in src are followed; a file or symlink at dst
+// is replaced rather than written through.
 func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	// A symlink at dst could point outside the worktree
+	if info, err := os.Lstat(dst); err == nil && !info.IsDir() {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	// Clone when the filesystem supports it, else fall back to copying
+	if reflinkDisabled || reflink(src, dst) != nil {
+		if err := copyContents(src, dst); err != nil {
+			return err
+		}
+	}
+
+	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
 	}
-	defer srcFile.Close()
 
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+	if err := os.Chmod(dst, srcInfo.Mode()); err != nil {
 		return err
 	}
+	// A zero access time leaves it unchanged
+	return os.Chtimes(dst, time.Time{}, srcInfo.ModTime())
+}
 
-	dstFile, err := os.Create(dst)
+// copyContents writes the contents of src to a new file dst, which must not
+// exist. The file is created private; copyFile sets its mode afterwards.
+func copyContents(src, dst string) error {
+	srcFile, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
+	defer srcFile.Close()
 
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
 	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
 		return err
 	}
+	return dstFile.Close()
+}
 
-	srcInfo, err := os.Stat(src)
+// copySymlink recreates the symlink src at dst with the same target,
+// replacing an existing file at dst.
+func copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
 	if err != nil {
 		return err
 	}
 
-	return os.Chmod(dst, srcInfo.Mode())
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+
+	return os.Symlink(target, dst)
 }
 
-func copyDir(src, dst string) error {
+// copyDir copies the tree at src to dst, recording each file in progress.
+// Symlinks inside the tree are recreated rather than followed, so relative
+// links keep pointing within the copy.
+func copyDir(src, dst string, progress *copyProgress) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -84,23 +217,201 @@ func copyDir(src, dst string) error {
 			return os.MkdirAll(dstPath, info.Mode())
 		}
 
-		return copyFile(path, dstPath)
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := copySymlink(path, dstPath); err != nil {
+				return err
+			}
+			progress.add(0)
+			return nil
+		}
+
+		if err := copyFile(path, dstPath); err != nil {
+			return err
+		}
+		progress.add(info.Size())
+		return nil
 	})
 }
 
-// RunPostHooks executes commands in the specified directory.
-func RunPostHooks(dir string, commands []string) error {
-	for _, cmdStr := range commands {
-		fmt.Printf("  running: %s\n", cmdStr)
+// LinkFiles creates relative symlinks in dst pointing back to files and
+// directories in src. Existing destinations are left untouched.
+func LinkFiles(src, dst string, files []string) error {
+	for _, file := range files {
+		srcPath := filepath.Join(src, file)
+		dstPath := filepath.Join(dst, file)
 
-		cmd := exec.Command("sh", "-c", cmdStr)
-		cmd.Dir = dir
+		if _, err := os.Stat(srcPath); os.IsNotExist(err) {
+			output.Printf("  skipping %s (not found)\n", file)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("stat %s: %w", srcPath, err)
+		}
+
+		if _, err := os.Lstat(dstPath); err == nil {
+			output.Printf("  skipping %s (already exists)\n", file)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return fmt.Errorf("create parent of %s: %w", file, err)
+		}
+
+		target, err := filepath.Rel(filepath.Dir(dstPath), srcPath)
+		if err != nil {
+			return fmt.Errorf("resolve link target for %s: %w", file, err)
+		}
+
+		if err := os.Symlink(target, dstPath); err != nil {
+			return fmt.Errorf("link %s: %w", file, err)
+		}
+		output.Printf("  linked %s -> %s\n", file, target)
+	}
+	return nil
+}
+
+// Env describes the worktree hooks run for. It is exposed to hook commands
+// as WK_* environment variables.
+type Env struct {
+	Branch       string
+	WorktreePath string
+	SourcePath   string
+	RepoName     string
+}
+
+// Vars returns the environment variables for e in KEY=value form.
+func (e Env) Vars() []string {
+	return []string{
+		"WK_BRANCH=" + e.Branch,
+		"WK_WORKTREE_PATH=" + e.WorktreePath,
+		"WK_SOURCE_PATH=" + e.SourcePath,
+		"WK_REPO_NAME=" + e.RepoName,
+	}
+}
+
+// ShellVars returns the WK_* variables followed by the shellEnv entries,
+// rendered as templates with e as data, in KEY=value form sorted by key.
+// Templates may use {{port N}} for a port derived from N and the branch name.
+func (e Env) ShellVars(shellEnv map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(shellEnv))
+	for k := range shellEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	funcs := template.FuncMap{"port": e.port}
+	vars := e.Vars()
+	for _, k := range keys {
+		tmpl, err := template.New(k).Funcs(funcs).Option("missingkey=error").Parse(shellEnv[k])
+		if err != nil {
+			return nil, fmt.Errorf("shell_env %s: %w", k, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("shell_env %s: %w", k, err)
+		}
+		vars = append(vars, k+"="+buf.String())
+	}
+	return vars, nil
+}
+
+// port returns base plus an offset in [0, 1000) derived from the branch name,
+// giving each worktree a stable, usually distinct port. Two branches can
+// hash to the same offset.
+func (e Env) port(base int) int {
+	h := fnv.New32a()
+	h.Write([]byte(e.Branch))
+	return base + int(h.Sum32()%1000)
+}
+
+// RunPostHooks executes hooks in the specified directory, with the
+// variables from env added to the inherited environment. A hook's Dir is
+// resolved relative to dir and must stay inside it. Hooks whose When doesn't
+// match env.Branch are skipped. Hooks marked Once are skipped if they already
+// ran for the worktree at dir, unless rerunOnce is set. When ctx is done, the
+// running hook is killed and no more hooks run.
+func RunPostHooks(ctx context.Context, dir string, list []config.Hook, env Env, rerunOnce bool) error {
+	record, err := loadOnceRecord(dir)
+	if err != nil {
+		return err
+	}
+	record.rerun = rerunOnce
+	return runEach(ctx, dir, list, env, "running", "command", record)
+}
+
+// RunVerify runs the verify checks in dir with the same environment, Dir and
+// When handling as RunPostHooks. Once is ignored: checks run every time.
+func RunVerify(ctx context.Context, dir string, list []config.Hook, env Env) error {
+	return runEach(ctx, dir, list, env, "checking", "check", nil)
+}
+
+// RunMainHooks runs the post_create_main hooks in dir, the main worktree,
+// like RunVerify. env describes the new worktree.
+func RunMainHooks(ctx context.Context, dir string, list []config.Hook, env Env) error {
+	return runEach(ctx, dir, list, env, "running", "command", nil)
+}
+
+// runEach runs each hook in list that matches env.Branch, printing verb and
+// the command first, and stops at the first failure, naming it as what.
+// Once hooks already in once are skipped, and are added to it when they
+// succeed; a nil once runs every hook.
+func runEach(ctx context.Context, dir string, list []config.Hook, env Env, verb, what string, once *onceRecord) error {
+	if err := CheckShell(); err != nil {
+		return err
+	}
+
+	for _, hook := range list {
+		runDir, err := hookDir(dir, hook.Dir)
+		if err != nil {
+			return err
+		}
+
+		if ok, _ := hook.Matches(env.Branch); !ok {
+			output.Printf("  skipping: %s (condition not met)\n", hook.Run)
+			continue
+		}
+
+		if once != nil && hook.Once && once.skip(hook) {
+			output.Printf("  skipping: %s (once, already run)\n", hook.Run)
+			continue
+		}
+
+		if hook.Name != "" {
+			output.Printf("  [%s]\n", hook.Name)
+		}
+		output.Printf("  %s: %s\n", verb, hook.Run)
+
+		cmd, err := shellCommand(ctx, hook.Run)
+		if err != nil {
+			return err
+		}
+		cmd.Dir = runDir
+		cmd.Env = append(os.Environ(), env.Vars()...)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 
 		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("command %q failed: %w", cmdStr, err)
+			return fmt.Errorf("%s %q failed: %w", what, hook.Run, err)
+		}
+
+		if once != nil && hook.Once {
+			if err := once.add(onceKey(hook)); err != nil {
+				return fmt.Errorf("record once hook: %w", err)
+			}
 		}
 	}
 	return nil
 }
+
+// hookDir resolves sub relative to root, rejecting paths outside root.
+func hookDir(root, sub string) (string, error) {
+	if sub == "" {
+		return root, nil
+	}
+
+	path := filepath.Join(root, sub)
+	rel, err := filepath.Rel(root, path)
+	if err != nil || filepath.IsAbs(sub) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("hook dir %q is outside the worktree", sub)
+	}
+	return path, nil
+}
@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-stellet/wk/internal/config"
+)
+
+// writeFile writes content to path, creating its parent directories.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the contents of path, or "" if it doesn't exist.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCopyFilesDirCopyMode(t *testing.T) {
+	tests := []struct {
+		mode config.DirCopyMode
+		// Contents of the destination's files afterwards
+		shared, srcOnly, dstOnly string
+	}{
+		{mode: "", shared: "new", srcOnly: "src", dstOnly: "dst"},
+		{mode: config.DirCopyMerge, shared: "new", srcOnly: "src", dstOnly: "dst"},
+		{mode: config.DirCopyReplace, shared: "new", srcOnly: "src", dstOnly: ""},
+		{mode: config.DirCopySkip, shared: "old", srcOnly: "", dstOnly: "dst"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			writeFile(t, filepath.Join(src, "tmp", "shared"), "new")
+			writeFile(t, filepath.Join(src, "tmp", "src-only"), "src")
+			writeFile(t, filepath.Join(dst, "tmp", "shared"), "old")
+			writeFile(t, filepath.Join(dst, "tmp", "dst-only"), "dst")
+
+			if err := CopyFiles(src, dst, []config.CopyEntry{{From: "tmp"}}, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := readFile(t, filepath.Join(dst, "tmp", "shared")); got != tt.shared {
+				t.Errorf("shared = %q, want %q", got, tt.shared)
+			}
+			if got := readFile(t, filepath.Join(dst, "tmp", "src-only")); got != tt.srcOnly {
+				t.Errorf("src-only = %q, want %q", got, tt.srcOnly)
+			}
+			if got := readFile(t, filepath.Join(dst, "tmp", "dst-only")); got != tt.dstOnly {
+				t.Errorf("dst-only = %q, want %q", got, tt.dstOnly)
+			}
+		})
+	}
+}
+
+func TestCopyFilesSymlinks(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(src, "tmp", "data", "file"), "data")
+	if err := os.Symlink("data/file", filepath.Join(src, "tmp", "file-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("data", filepath.Join(src, "tmp", "dir-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyFiles(src, dst, []config.CopyEntry{{From: "tmp"}}, config.DirCopyMerge); err != nil {
+		t.Fatal(err)
+	}
+
+	for link, want := range map[string]string{"file-link": "data/file", "dir-link": "data"} {
+		got, err := os.Readlink(filepath.Join(dst, "tmp", link))
+		if err != nil {
+			t.Errorf("%s: %v", link, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s -> %q, want %q", link, got, want)
+		}
+	}
+	if got := readFile(t, filepath.Join(dst, "tmp", "dir-link", "file")); got != "data" {
+		t.Errorf("dir-link/file = %q, want %q", got, "data")
+	}
+}
+
+func TestCopyFilesReplacesSymlinkAtDestination(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		SetReflink(enabled)
+		t.Cleanup(func() { SetReflink(true) })
+
+		src, dst, outside := t.TempDir(), t.TempDir(), t.TempDir()
+		writeFile(t, filepath.Join(src, ".env"), "new")
+		if err := os.Chmod(filepath.Join(src, ".env"), 0640); err != nil {
+			t.Fatal(err)
+		}
+		target := filepath.Join(outside, "secret")
+		writeFile(t, target, "outside")
+		if err := os.Symlink(target, filepath.Join(dst, ".env")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CopyFiles(src, dst, []config.CopyEntry{{From: ".env"}}, config.DirCopyMerge); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := readFile(t, target); got != "outside" {
+			t.Errorf("reflink %v: file outside the worktree = %q, want it unchanged", enabled, got)
+		}
+		info, err := os.Lstat(filepath.Join(dst, ".env"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm() != 0640 {
+			t.Errorf("reflink %v: .env mode = %v, want a regular file with 0640", enabled, info.Mode())
+		}
+		if got := readFile(t, filepath.Join(dst, ".env")); got != "new" {
+			t.Errorf("reflink %v: .env = %q, want %q", enabled, got, "new")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package hooks
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+// onceRecordFile is the file, inside a worktree's git admin directory, that
+// lists the once-hooks already run for that worktree. Keeping it there means
+// it is removed together with the worktree.
+const onceRecordFile = "wk-once-hooks"
+
+// onceRecord tracks which once-hooks have completed for a worktree.
+type onceRecord struct {
+	path string
+	done map[string]bool
+	// rerun runs once-hooks again even if they are in done.
+	rerun bool
+}
+
+// onceKey identifies a hook by its command and directory.
+func onceKey(h config.Hook) string {
+	sum := sha256.Sum256([]byte(h.Dir + "\x00" + h.Run))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadOnceRecord reads the once-hook record for the worktree at dir.
+func loadOnceRecord(dir string) (*onceRecord, error) {
+	gitDir, err := worktree.GetGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &onceRecord{
+		path: filepath.Join(gitDir, onceRecordFile),
+		done: make(map[string]bool),
+	}
+
+	f, err := os.Open(r.path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read once-hook record: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r.done[scanner.Text()] = true
+	}
+	return r, scanner.Err()
+}
+
+// skip reports whether the once-hook h has already run and shouldn't run
+// again.
+func (r *onceRecord) skip(h config.Hook) bool {
+	return !r.rerun && r.done[onceKey(h)]
+}
+
+// add marks key as done and appends it to the record file.
+func (r *onceRecord) add(key string) error {
+	if r.done[key] {
+		return nil
+	}
+
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, key); err != nil {
+		f.Close()
+		return err
+	}
+	r.done[key] = true
+	return f.Close()
+}
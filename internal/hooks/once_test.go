@@ -0,0 +1,44 @@
+package hooks
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/worktree"
+)
+
+func TestRunPostHooksOnce(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv(worktree.GitBinEnv, "")
+
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "--quiet", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	list := []config.Hook{
+		{Run: "echo once >> log", Once: true},
+		{Run: "echo always >> log"},
+	}
+	runs := []struct {
+		rerunOnce bool
+		want      string
+	}{
+		{false, "once always"},
+		{false, "once always always"},
+		{true, "once always always once always"},
+	}
+	for i, run := range runs {
+		if err := RunPostHooks(context.Background(), dir, list, Env{}, run.rerunOnce); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+		if got := strings.Join(strings.Fields(readFile(t, filepath.Join(dir, "log"))), " "); got != run.want {
+			t.Errorf("run %d: log = %q, want %q", i+1, got, run.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	// progressMinFiles is the number of files from which copying a
+	// directory reports progress; smaller copies print a single line.
+	progressMinFiles = 500
+	// progressInterval is the minimum time between progress updates.
+	progressInterval = 200 * time.Millisecond
+)
+
+// copyProgress reports how much of a directory has been copied. A nil
+// *copyProgress reports nothing.
+type copyProgress struct {
+	name  string
+	total int
+	files int
+	bytes int64
+	last  time.Time
+	// live rewrites the progress line in place; off when stdout isn't a
+	// terminal, so logs only get the final summary.
+	live bool
+}
+
+// newCopyProgress counts the files in dir and returns a progress reporter
+// for it, or nil if dir has fewer than progressMinFiles files.
+func newCopyProgress(name, dir string) *copyProgress {
+	total := countFiles(dir)
+	if total < progressMinFiles {
+		return nil
+	}
+	return &copyProgress{
+		name:  name,
+		total: total,
+		live:  term.IsTerminal(os.Stdout.Fd()),
+	}
+}
+
+// add records a copied file of size bytes, printing an update if the last
+// one is older than progressInterval.
+func (p *copyProgress) add(size int64) {
+	if p == nil {
+		return
+	}
+	p.files++
+	p.bytes += size
+
+	if !p.live || time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+
+	// The count is approximate, as files may change while copying
+	percent := min(100, p.files*100/p.total)
+	fmt.Printf("\r  copying %s: %d/%d files (%d%%), %s", p.name, p.files, p.total, percent, formatBytes(p.bytes))
+}
+
+// clear erases the progress line, if one was printed.
+func (p *copyProgress) clear() {
+	if p != nil && p.live && !p.last.IsZero() {
+		fmt.Print("\r\033[K")
+	}
+}
+
+// summary returns the files and bytes copied, e.g. "5210 files, 48.3 MB".
+func (p *copyProgress) summary() string {
+	return fmt.Sprintf("%d files, %s", p.files, formatBytes(p.bytes))
+}
+
+// countFiles returns the number of non-directory entries under dir. Errors
+// are ignored, since the count is only used for the progress percentage.
+func countFiles(dir string) int {
+	var n int
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			n++
+		}
+		return nil
+	})
+	return n
+}
+
+// formatBytes formats n bytes using binary units, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
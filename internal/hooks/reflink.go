@@ -0,0 +1,12 @@
+package hooks
+
+// reflinkDisabled is set via SetReflink.
+var reflinkDisabled bool
+
+// SetReflink sets whether copied files are cloned with a copy-on-write
+// reflink when the filesystem supports it (e.g. Btrfs, XFS, APFS). A clone
+// is near-instant and shares the data until either copy changes. Enabled by
+// default; files are copied byte by byte when disabled or unsupported.
+func SetReflink(enabled bool) {
+	reflinkDisabled = !enabled
+}
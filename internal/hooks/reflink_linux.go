@@ -0,0 +1,31 @@
+package hooks
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src to a new file dst with the FICLONE ioctl. Like
+// copyContents, it creates dst private and fails if dst exists; it also fails
+// when src and dst are on different filesystems or the filesystem doesn't
+// support it, leaving no dst behind.
+func reflink(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := unix.IoctlFileClone(int(dstFile.Fd()), int(srcFile.Fd())); err != nil {
+		dstFile.Close()
+		os.Remove(dst)
+		return err
+	}
+	return dstFile.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package hooks
+
+import "errors"
+
+// reflink is not supported on this platform.
+func reflink(src, dst string) error {
+	return errors.New("reflink not supported")
+}
@@ -0,0 +1,57 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lucas-stellet/wk/internal/output"
+)
+
+// RemoveEvent describes a removed worktree. It is passed to on_remove
+// commands as JSON on stdin.
+type RemoveEvent struct {
+	Event  string `json:"event"`
+	Path   string `json:"path"`
+	Branch string `json:"branch"`
+	Repo   string `json:"repo"`
+}
+
+// RunOnRemove runs commands in dir (the main worktree, since the removed one
+// no longer exists), each receiving event as JSON on stdin and the WK_*
+// variables for the removed worktree in its environment.
+func RunOnRemove(dir string, commands []string, event RemoveEvent) error {
+	event.Event = "remove"
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode remove event: %w", err)
+	}
+
+	env := Env{
+		Branch:       event.Branch,
+		WorktreePath: event.Path,
+		SourcePath:   dir,
+		RepoName:     event.Repo,
+	}
+
+	for _, command := range commands {
+		output.Printf("  running: %s\n", command)
+
+		cmd, err := shellCommand(context.Background(), command)
+		if err != nil {
+			return err
+		}
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env.Vars()...)
+		cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("command %q failed: %w", command, err)
+		}
+	}
+	return nil
+}
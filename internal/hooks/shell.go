@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// shell is the shell hook commands run with; empty means the platform
+// default (see defaultShell).
+var shell string
+
+// SetShell sets the shell hook commands run with: a name looked up on PATH,
+// like "bash", or a full path. Empty restores the platform default.
+func SetShell(name string) {
+	shell = name
+}
+
+// defaultShell returns sh, or on Windows cmd.exe (%COMSPEC%) if available and
+// PowerShell otherwise.
+func defaultShell() string {
+	if runtime.GOOS != "windows" {
+		return "sh"
+	}
+	if comspec := os.Getenv("COMSPEC"); comspec != "" {
+		return comspec
+	}
+	if _, err := exec.LookPath("cmd"); err == nil {
+		return "cmd"
+	}
+	return "powershell"
+}
+
+// CheckShell returns an error naming the configured shell if it can't be
+// found, so callers can fail before running any hook.
+func CheckShell() error {
+	_, _, err := lookShell()
+	return err
+}
+
+// lookShell returns the configured shell's name and path.
+func lookShell() (name, path string, err error) {
+	name = shell
+	if name == "" {
+		name = defaultShell()
+	}
+
+	path, err = exec.LookPath(name)
+	if err != nil {
+		return name, "", fmt.Errorf("shell %q not found; install it or change 'shell' in .wk.yaml", name)
+	}
+	return name, path, nil
+}
+
+// shellCommand returns a command that runs command with the configured
+// shell, or an error naming the shell if it can't be found. The command is
+// killed if ctx is done before it exits.
+func shellCommand(ctx context.Context, command string) (*exec.Cmd, error) {
+	name, path, err := lookShell()
+	if err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, path, shellFlag(name), command), nil
+}
+
+// shellFlag returns the flag that makes the shell name run a command string.
+func shellFlag(name string) string {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+	switch base {
+	case "cmd":
+		return "/c"
+	case "powershell", "pwsh":
+		return "-Command"
+	}
+	return "-c"
+}
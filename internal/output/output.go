@@ -0,0 +1,94 @@
+// Package output controls how much wk prints, as set by the global --quiet
+// and --verbose flags.
+package output
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Level is the amount of output wk prints.
+type Level int
+
+const (
+	// Quiet prints only errors and essential results.
+	Quiet Level = iota
+	// Normal also prints progress messages and hints.
+	Normal
+	// Verbose also prints details such as the git commands being run.
+	Verbose
+)
+
+var level = Normal
+
+// SetLevel sets the output level.
+func SetLevel(l Level) {
+	level = l
+}
+
+// IsQuiet reports whether only errors and essential results are printed.
+func IsQuiet() bool {
+	return level == Quiet
+}
+
+// IsVerbose reports whether details are printed.
+func IsVerbose() bool {
+	return level >= Verbose
+}
+
+// Printf prints a progress message to stdout unless quiet.
+func Printf(format string, a ...any) {
+	if level >= Normal {
+		fmt.Printf(format, a...)
+	}
+}
+
+// Println prints a progress message to stdout unless quiet.
+func Println(a ...any) {
+	if level >= Normal {
+		fmt.Println(a...)
+	}
+}
+
+// Verbosef prints a detail message to stdout in verbose mode.
+func Verbosef(format string, a ...any) {
+	if level >= Verbose {
+		fmt.Printf(format, a...)
+	}
+}
+
+// Hintf prints a hint to stderr unless quiet.
+func Hintf(format string, a ...any) {
+	if level >= Normal {
+		fmt.Fprintf(os.Stderr, "hint: "+format, a...)
+	}
+}
+
+// FormatCommand joins args into a command line, quoting arguments that
+// contain spaces or shell metacharacters.
+func FormatCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'$\\*?{}") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Command prints cmd to stderr in verbose mode, before it runs.
+func Command(cmd *exec.Cmd) {
+	if level < Verbose {
+		return
+	}
+
+	line := "+ " + FormatCommand(cmd.Args)
+	if cmd.Dir != "" {
+		line += "  (in " + cmd.Dir + ")"
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
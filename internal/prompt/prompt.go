@@ -0,0 +1,57 @@
+// Package prompt asks the user yes/no questions. With --yes every question is
+// answered yes without asking, so commands can run unattended.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var assumeYes bool
+
+// SetAssumeYes makes Confirm and ConfirmDefaultYes answer yes without asking.
+func SetAssumeYes(yes bool) {
+	assumeYes = yes
+}
+
+// AssumeYes reports whether questions are answered yes without asking.
+func AssumeYes() bool {
+	return assumeYes
+}
+
+// Confirm prints question followed by "[y/N]" and reports whether the user
+// answered y or yes. Anything else, including no input, is no.
+func Confirm(question string) bool {
+	return ConfirmTo(os.Stdout, question)
+}
+
+// ConfirmTo is like Confirm but prints question to w. Use os.Stderr when
+// stdout is reserved for machine-readable output.
+func ConfirmTo(w io.Writer, question string) bool {
+	if assumeYes {
+		return true
+	}
+	fmt.Fprintf(w, "%s [y/N]: ", question)
+	answer := readAnswer()
+	return answer == "y" || answer == "yes"
+}
+
+// ConfirmDefaultYes is like Confirm, but only n or no is an answer of no.
+func ConfirmDefaultYes(question string) bool {
+	if assumeYes {
+		return true
+	}
+	fmt.Printf("%s [Y/n]: ", question)
+	answer := readAnswer()
+	return answer != "n" && answer != "no"
+}
+
+// readAnswer reads a line from stdin, trimmed and lowercased.
+func readAnswer() string {
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(input))
+}
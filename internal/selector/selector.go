@@ -2,25 +2,84 @@
 package selector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/state"
 	"github.com/lucas-stellet/wk/internal/worktree"
 )
 
-// ErrCancelled is returned when the user cancels the selection.
-var ErrCancelled = errors.New("selection cancelled")
+// ErrCancelled is returned when the user cancels the selection. It makes wk
+// exit with exit.Cancelled without printing an error.
+var ErrCancelled = exit.WithCode(exit.Cancelled, errors.New("selection cancelled"))
 
 // Options configures the branch selector behavior.
 type Options struct {
-	AllowCreate    bool // shows "[+] Create new branch..." option
-	FilterExisting bool // filters out branches that already have worktrees
+	AllowCreate    bool     // shows "[+] Create new branch..." option
+	FilterExisting bool     // filters out branches that already have worktrees
+	Sort           SortMode // branch order; empty keeps local branches first
+}
+
+// SortMode orders branches in the selector.
+type SortMode string
+
+const (
+	SortName   SortMode = "name"   // alphabetical
+	SortDate   SortMode = "date"   // most recent commit first
+	SortStatus SortMode = "status" // synced, then local-only, then remote-only
+)
+
+// SortModes lists the valid sort modes.
+var SortModes = []SortMode{SortName, SortDate, SortStatus}
+
+// sortBranches orders branches by mode, breaking ties by name.
+func sortBranches(branches []worktree.Branch, mode SortMode) {
+	var less func(a, b worktree.Branch) bool
+	switch mode {
+	case SortName:
+		less = func(a, b worktree.Branch) bool { return false }
+	case SortDate:
+		less = func(a, b worktree.Branch) bool { return a.CommitDate.After(b.CommitDate) }
+	case SortStatus:
+		less = func(a, b worktree.Branch) bool { return statusRank(a) < statusRank(b) }
+	default:
+		return
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		a, b := branches[i], branches[j]
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Name < b.Name
+	})
+}
+
+// statusRank orders branch statuses for SortStatus.
+func statusRank(b worktree.Branch) int {
+	switch {
+	case b.IsLocal && b.IsRemote:
+		return 0
+	case b.IsLocal:
+		return 1
+	}
+	return 2
 }
 
 // branchItem represents a branch in the list.
@@ -39,14 +98,20 @@ type worktreeItem struct {
 	branch string
 	path   string
 	commit string
+	index  int // position in git worktree list
 }
 
 func (i worktreeItem) Title() string       { return i.branch }
 func (i worktreeItem) Description() string { return i.path }
 func (i worktreeItem) FilterValue() string { return i.branch }
 
-// Custom delegate for our list styling
-type itemDelegate struct{}
+// Custom delegate for our list styling.
+// When checked is non-nil, items render with a checkbox for multi-select.
+// Worktrees whose path is in pinned render with a pin marker.
+type itemDelegate struct {
+	checked map[string]bool
+	pinned  map[string]bool
+}
 
 func (d itemDelegate) Height() int                             { return 2 }
 func (d itemDelegate) Spacing() int                            { return 0 }
@@ -56,6 +121,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	var (
 		title, desc string
 		isCreate    bool
+		isPinned    bool
 	)
 
 	switch i := listItem.(type) {
@@ -66,6 +132,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	case worktreeItem:
 		title = i.branch
 		desc = i.path
+		isPinned = d.pinned[i.path]
 	}
 
 	// Styles
@@ -73,6 +140,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
 	createStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("78"))
+	pinStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
 
 	isSelected := index == m.Index()
 
@@ -84,11 +152,16 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		indicator = " "
 	}
 
-	// Circle/bullet
+	// Circle/bullet, or checkbox in multi-select mode
 	var bullet string
-	if isSelected {
+	switch {
+	case d.checked != nil && d.checked[title]:
+		bullet = selectedStyle.Render("[x]")
+	case d.checked != nil:
+		bullet = dimStyle.Render("[ ]")
+	case isSelected:
 		bullet = selectedStyle.Render("●")
-	} else {
+	default:
 		bullet = dimStyle.Render("○")
 	}
 
@@ -102,6 +175,10 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		titleStr = normalStyle.Render(title)
 	}
 
+	if isPinned {
+		titleStr += " " + pinStyle.Render("★")
+	}
+
 	// Description styling
 	descStr := dimStyle.Render(desc)
 
@@ -115,6 +192,13 @@ type selectorModel struct {
 	choice   string
 	isCreate bool
 	quitting bool
+
+	// multi-select mode: checked holds toggled branches, choices the result
+	checked map[string]bool
+	choices []string
+
+	// pinned holds pinned worktree paths; nil disables pinning
+	pinned map[string]bool
 }
 
 func (m selectorModel) Init() tea.Cmd {
@@ -139,7 +223,29 @@ func (m selectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 
+		case " ":
+			if m.checked == nil || m.list.FilterState() == list.Filtering {
+				break
+			}
+			if item, ok := m.list.SelectedItem().(worktreeItem); ok {
+				m.checked[item.branch] = !m.checked[item.branch]
+			}
+			return m, nil
+
+		case "p":
+			if m.pinned == nil || m.list.FilterState() == list.Filtering {
+				break
+			}
+			if item, ok := m.list.SelectedItem().(worktreeItem); ok {
+				m.togglePin(item)
+			}
+			return m, nil
+
 		case "enter":
+			if m.checked != nil {
+				m.choices = m.checkedChoices()
+				return m, tea.Quit
+			}
 			if item, ok := m.list.SelectedItem().(branchItem); ok {
 				m.choice = item.name
 				m.isCreate = item.isCreate
@@ -155,6 +261,61 @@ func (m selectorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// togglePin pins or unpins item, persists the change and moves pinned
+// worktrees back to the top, keeping item highlighted.
+func (m *selectorModel) togglePin(item worktreeItem) {
+	pin := !m.pinned[item.path]
+	if err := state.SetPinned(item.path, pin); err != nil {
+		m.list.NewStatusMessage(fmt.Sprintf("Cannot save pin: %v", err))
+		return
+	}
+	if pin {
+		m.pinned[item.path] = true
+	} else {
+		delete(m.pinned, item.path)
+	}
+
+	items := m.list.Items()
+	sortPinned(items, m.pinned)
+	m.list.SetItems(items)
+	for i, li := range items {
+		if wt, ok := li.(worktreeItem); ok && wt.path == item.path {
+			m.list.Select(i)
+			break
+		}
+	}
+}
+
+// sortPinned moves pinned worktrees first, keeping the worktree list order
+// (given by the index field) within each group.
+func sortPinned(items []list.Item, pinned map[string]bool) {
+	sort.SliceStable(items, func(a, b int) bool {
+		wa, _ := items[a].(worktreeItem)
+		wb, _ := items[b].(worktreeItem)
+		if pinned[wa.path] != pinned[wb.path] {
+			return pinned[wa.path]
+		}
+		return wa.index < wb.index
+	})
+}
+
+// checkedChoices returns the toggled branches in list order.
+// Falls back to the highlighted item when nothing was toggled.
+func (m selectorModel) checkedChoices() []string {
+	var choices []string
+	for _, li := range m.list.Items() {
+		if item, ok := li.(worktreeItem); ok && m.checked[item.branch] {
+			choices = append(choices, item.branch)
+		}
+	}
+	if len(choices) == 0 {
+		if item, ok := m.list.SelectedItem().(worktreeItem); ok {
+			choices = append(choices, item.branch)
+		}
+	}
+	return choices
+}
+
 func (m selectorModel) View() string {
 	if m.quitting {
 		return ""
@@ -167,6 +328,7 @@ func SelectBranch(opts Options) (string, error) {
 	selected, _, err := SelectOrCreate(Options{
 		AllowCreate:    false,
 		FilterExisting: opts.FilterExisting,
+		Sort:           opts.Sort,
 	})
 	return selected, err
 }
@@ -177,6 +339,7 @@ func SelectOrCreate(opts Options) (string, bool, error) {
 	if err != nil {
 		return "", false, fmt.Errorf("list branches: %w", err)
 	}
+	sortBranches(branches, opts.Sort)
 
 	var existingWorktrees map[string]bool
 	if opts.FilterExisting {
@@ -202,7 +365,7 @@ func SelectOrCreate(opts Options) (string, bool, error) {
 		}
 
 		status := formatBranchStatus(b)
-		desc := fmt.Sprintf("%s · %s · %s", status, b.CommitShort, b.CommitDate)
+		desc := fmt.Sprintf("%s · %s · %s", status, b.CommitShort, output.RelativeTime(b.CommitDate))
 		items = append(items, branchItem{
 			name:        b.Name,
 			description: desc,
@@ -213,17 +376,7 @@ func SelectOrCreate(opts Options) (string, bool, error) {
 		return "", false, errors.New("no branches available")
 	}
 
-	l := list.New(items, itemDelegate{}, 80, 20)
-	l.Title = "Select branch"
-	l.SetShowStatusBar(true)
-	l.SetFilteringEnabled(true)
-	l.Styles.Title = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("252")).
-		MarginLeft(2)
-	l.Styles.FilterPrompt = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	l.Styles.FilterCursor = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
-	l.SetShowHelp(true)
+	l := newList(items, itemDelegate{}, "Select branch")
 
 	m := selectorModel{list: l}
 	p := tea.NewProgram(m, tea.WithAltScreen())
@@ -241,28 +394,10 @@ func SelectOrCreate(opts Options) (string, bool, error) {
 	return result.choice, result.isCreate, nil
 }
 
-// SelectWorktree opens an interactive selector for existing worktrees.
-func SelectWorktree() (string, error) {
-	worktrees, err := worktree.List()
-	if err != nil {
-		return "", fmt.Errorf("list worktrees: %w", err)
-	}
-
-	if len(worktrees) == 0 {
-		return "", errors.New("no worktrees found")
-	}
-
-	var items []list.Item
-	for _, wt := range worktrees {
-		items = append(items, worktreeItem{
-			branch: wt.Branch,
-			path:   wt.Path,
-			commit: wt.Commit,
-		})
-	}
-
-	l := list.New(items, itemDelegate{}, 80, 20)
-	l.Title = "Select worktree"
+// newList creates a list with the shared selector styling.
+func newList(items []list.Item, delegate list.ItemDelegate, title string) list.Model {
+	l := list.New(items, delegate, 80, 20)
+	l.Title = title
 	l.SetShowStatusBar(true)
 	l.SetFilteringEnabled(true)
 	l.Styles.Title = lipgloss.NewStyle().
@@ -272,9 +407,60 @@ func SelectWorktree() (string, error) {
 	l.Styles.FilterPrompt = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	l.Styles.FilterCursor = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
 	l.SetShowHelp(true)
+	return l
+}
 
-	m := selectorModel{list: l}
-	p := tea.NewProgram(m, tea.WithAltScreen())
+// SelectWorktree opens an interactive selector for existing worktrees.
+func SelectWorktree() (string, error) {
+	return SelectWorktreeTo(os.Stdout)
+}
+
+// SelectWorktreeTo is like SelectWorktree but renders the selector to out.
+// Use os.Stderr when stdout is reserved for machine-readable output.
+func SelectWorktreeTo(out io.Writer) (string, error) {
+	items, err := worktreeItems()
+	if err != nil {
+		return "", err
+	}
+	return selectWorktree(out, items, "Select worktree")
+}
+
+// SelectWorktreeAmong is like SelectWorktreeTo but only offers the worktrees
+// of branches, e.g. the close matches of a mistyped branch named in the
+// title.
+func SelectWorktreeAmong(out io.Writer, branches []string, title string) (string, error) {
+	items, err := worktreeItems()
+	if err != nil {
+		return "", err
+	}
+
+	var among []list.Item
+	for _, branch := range branches {
+		for _, item := range items {
+			if item.(worktreeItem).branch == branch {
+				among = append(among, item)
+			}
+		}
+	}
+	if len(among) == 0 {
+		return "", errors.New("no worktrees found")
+	}
+	return selectWorktree(out, among, title)
+}
+
+// selectWorktree runs the worktree selector over items, rendered to out.
+func selectWorktree(out io.Writer, items []list.Item, title string) (string, error) {
+	// A broken pins file shouldn't block selection; start with no pins
+	pinned, _ := state.Pins()
+	sortPinned(items, pinned)
+
+	l := newList(items, itemDelegate{pinned: pinned}, title)
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin"))}
+	}
+
+	m := selectorModel{list: l, pinned: pinned}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithOutput(out))
 
 	finalModel, err := p.Run()
 	if err != nil {
@@ -289,6 +475,95 @@ func SelectWorktree() (string, error) {
 	return result.choice, nil
 }
 
+// SelectWorktreeNumbered is a plain alternative to SelectWorktreeTo for
+// terminals the selector can't drive: it prints the worktrees to out,
+// numbered, and reads the number of the chosen one from stdin. An empty
+// answer cancels.
+func SelectWorktreeNumbered(out io.Writer) (string, error) {
+	items, err := worktreeItems()
+	if err != nil {
+		return "", err
+	}
+	pinned, _ := state.Pins()
+	sortPinned(items, pinned)
+
+	for i, item := range items {
+		wt := item.(worktreeItem)
+		fmt.Fprintf(out, "%3d) %s  %s\n", i+1, wt.branch, wt.path)
+	}
+	fmt.Fprintf(out, "Select worktree [1-%d]: ", len(items))
+
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", ErrCancelled
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(items) {
+		return "", fmt.Errorf("invalid choice '%s': enter a number from 1 to %d", input, len(items))
+	}
+	return items[n-1].(worktreeItem).branch, nil
+}
+
+// SelectWorktreesMulti opens an interactive selector where several worktrees
+// can be toggled with space. Returns the selected branches.
+func SelectWorktreesMulti() ([]string, error) {
+	items, err := worktreeItems()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, _ := state.Pins()
+	sortPinned(items, pinned)
+
+	checked := make(map[string]bool)
+	l := newList(items, itemDelegate{checked: checked, pinned: pinned}, "Select worktrees")
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "toggle")),
+			key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin")),
+		}
+	}
+
+	m := selectorModel{list: l, checked: checked, pinned: pinned}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	result := finalModel.(selectorModel)
+	if result.quitting && len(result.choices) == 0 {
+		return nil, ErrCancelled
+	}
+
+	return result.choices, nil
+}
+
+// worktreeItems returns list items for all worktrees.
+func worktreeItems() ([]list.Item, error) {
+	worktrees, err := worktree.List()
+	if err != nil {
+		return nil, fmt.Errorf("list worktrees: %w", err)
+	}
+
+	if len(worktrees) == 0 {
+		return nil, errors.New("no worktrees found")
+	}
+
+	var items []list.Item
+	for i, wt := range worktrees {
+		items = append(items, worktreeItem{
+			branch: wt.Branch,
+			path:   wt.Path,
+			commit: wt.Commit,
+			index:  i,
+		})
+	}
+	return items, nil
+}
+
 func formatBranchStatus(b worktree.Branch) string {
 	if b.IsLocal && b.IsRemote {
 		return "synced"
@@ -296,7 +571,7 @@ func formatBranchStatus(b worktree.Branch) string {
 	if b.IsLocal {
 		return "local"
 	}
-	return "remote"
+	return "remote: " + b.Remote
 }
 
 // promptForBranchName prompts the user for a new branch name.
@@ -0,0 +1,118 @@
+// Package state stores wk's persistent per-user state, such as pinned
+// worktrees, under ~/.wk or $WK_STATE_DIR. All state files must be placed in
+// Dir; data that can be recreated, like the update cache, goes in CacheDir.
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const pinsFileName = "pins.json"
+
+// DirEnv is the environment variable that overrides the state directory.
+const DirEnv = "WK_STATE_DIR"
+
+// Dir returns the wk state directory path: $WK_STATE_DIR if set, otherwise
+// ~/.wk.
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".wk"), nil
+}
+
+// CacheDir returns the wk cache directory path: $WK_STATE_DIR if set, so test
+// runs stay isolated, otherwise $XDG_CACHE_HOME/wk, falling back to
+// ~/.cache/wk.
+func CacheDir() (string, error) {
+	if os.Getenv(DirEnv) != "" {
+		return Dir()
+	}
+
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "wk"), nil
+}
+
+// Pins returns the set of pinned worktree paths.
+func Pins() (map[string]bool, error) {
+	pins := make(map[string]bool)
+
+	dir, err := Dir()
+	if err != nil {
+		return pins, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, pinsFileName))
+	if os.IsNotExist(err) {
+		return pins, nil
+	}
+	if err != nil {
+		return pins, err
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return pins, err
+	}
+	for _, p := range paths {
+		pins[p] = true
+	}
+	return pins, nil
+}
+
+// SetPinned pins or unpins the worktree at path.
+func SetPinned(path string, pinned bool) error {
+	pins, err := Pins()
+	if err != nil {
+		return err
+	}
+	if pins[path] == pinned {
+		return nil
+	}
+	if pinned {
+		pins[path] = true
+	} else {
+		delete(pins, path)
+	}
+	return savePins(pins)
+}
+
+// savePins writes pins to disk, sorted for stable output.
+func savePins(pins map[string]bool) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(pins))
+	for p := range pins {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, pinsFileName), data, 0644)
+}
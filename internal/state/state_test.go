@@ -0,0 +1,54 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		env, want string
+	}{
+		{"", filepath.Join(home, ".wk")},
+		{filepath.Join(home, "state"), filepath.Join(home, "state")},
+	}
+	for _, tt := range tests {
+		t.Setenv(DirEnv, tt.env)
+		if got, err := Dir(); err != nil || got != tt.want {
+			t.Errorf("%s=%q: Dir() = %q, %v; want %q", DirEnv, tt.env, got, err, tt.want)
+		}
+	}
+}
+
+func TestDirRelative(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv(DirEnv, "state")
+
+	if got, err := Dir(); err != nil || got != filepath.Join(dir, "state") {
+		t.Errorf("Dir() = %q, %v; want %q", got, err, filepath.Join(dir, "state"))
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		stateDir, xdg, want string
+	}{
+		{"", "", filepath.Join(home, ".cache", "wk")},
+		{"", filepath.Join(home, "xdg"), filepath.Join(home, "xdg", "wk")},
+		{filepath.Join(home, "state"), filepath.Join(home, "xdg"), filepath.Join(home, "state")},
+	}
+	for _, tt := range tests {
+		t.Setenv(DirEnv, tt.stateDir)
+		t.Setenv("XDG_CACHE_HOME", tt.xdg)
+		if got, err := CacheDir(); err != nil || got != tt.want {
+			t.Errorf("%s=%q XDG_CACHE_HOME=%q: CacheDir() = %q, %v; want %q", DirEnv, tt.stateDir, tt.xdg, got, err, tt.want)
+		}
+	}
+}
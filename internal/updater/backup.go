@@ -0,0 +1,149 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lucas-stellet/wk/internal/state"
+)
+
+const (
+	backupsDirName = "backups"
+	backupPrefix   = "wk-"
+	maxBackups     = 3
+)
+
+// Backup is a previously installed wk binary kept for rollback.
+type Backup struct {
+	Version string
+	Path    string
+}
+
+// getBackupsDir returns the directory holding previous binaries.
+func getBackupsDir() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, backupsDirName), nil
+}
+
+// saveBackup copies the binary at execPath to the backups directory as
+// wk-<version>, keeping only the most recent maxBackups entries.
+func saveBackup(execPath, version string) error {
+	dir, err := getBackupsDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	backupPath := filepath.Join(dir, backupPrefix+version)
+	if err := copyFile(execPath, backupPath); err != nil {
+		return err
+	}
+	if err := os.Chmod(backupPath, 0755); err != nil {
+		return err
+	}
+
+	backups, err := listBackups()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[min(len(backups), maxBackups):] {
+		os.Remove(b.Path)
+	}
+	return nil
+}
+
+// listBackups returns the saved binaries, most recent first.
+func listBackups() ([]Backup, error) {
+	dir, err := getBackupsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type dated struct {
+		Backup
+		modTime int64
+	}
+	var found []dated
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), backupPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, dated{
+			Backup: Backup{
+				Version: strings.TrimPrefix(e.Name(), backupPrefix),
+				Path:    filepath.Join(dir, e.Name()),
+			},
+			modTime: info.ModTime().UnixNano(),
+		})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime > found[j].modTime })
+
+	backups := make([]Backup, len(found))
+	for i, d := range found {
+		backups[i] = d.Backup
+	}
+	return backups, nil
+}
+
+// LatestBackup returns the most recently saved binary, or nil if none exists.
+func LatestBackup() (*Backup, error) {
+	backups, err := listBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, nil
+	}
+	return &backups[0], nil
+}
+
+// Rollback reinstalls the most recently saved binary over the running one,
+// verifying it like a regular update. The backup is removed once restored.
+func Rollback() error {
+	backup, err := LatestBackup()
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	if backup == nil {
+		return fmt.Errorf("no previous version available to roll back to")
+	}
+
+	execPath, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	if requiresElevatedPermissions(execPath) {
+		err = installWithSudo(backup.Path, execPath)
+	} else {
+		err = installDirect(backup.Path, execPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	os.Remove(backup.Path)
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/lucas-stellet/wk/internal/state"
 )
 
 const (
@@ -19,46 +21,81 @@ type CacheEntry struct {
 	LatestVersion   string    `json:"latest_version"`
 	UpdateAvailable bool      `json:"update_available"`
 	DownloadURL     string    `json:"download_url"`
+	ChecksumURL     string    `json:"checksum_url,omitempty"`
 	ReleaseURL      string    `json:"release_url"`
+	Channel         Channel   `json:"channel,omitempty"`
 }
 
-// CachedCheck returns cached update info if valid, otherwise fetches new info.
+// CachedCheck returns cached update info if valid, otherwise fetches new info
+// for the persisted update channel. Returns ErrOffline in offline mode.
 func CachedCheck(currentVersion string) (*Info, error) {
-	cache, err := loadCache()
-	if err == nil && cache.isValid(currentVersion) {
+	if Offline() {
+		return nil, ErrOffline
+	}
+
+	channel := LoadChannel()
+
+	cache, err := LoadCache()
+	if err == nil && cache.isValid(currentVersion, channel) {
 		return cache.toInfo(), nil
 	}
 
-	info, err := CheckForUpdate(currentVersion)
+	info, err := CheckForUpdate(currentVersion, channel)
 	if err != nil {
 		return nil, err
 	}
 
-	saveCache(info)
+	saveCache(info, channel)
 	return info, nil
 }
 
 // getCacheDir returns the wk cache directory path.
 func getCacheDir() (string, error) {
-	home, err := os.UserHomeDir()
+	return state.CacheDir()
+}
+
+// CachePath returns the full path to the cache file, first moving a cache
+// left in the state directory by older versions of wk.
+func CachePath() (string, error) {
+	dir, err := getCacheDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ".wk"), nil
+	path := filepath.Join(dir, cacheFileName)
+	migrateCache(path)
+	return path, nil
 }
 
-// getCachePath returns the full path to the cache file.
-func getCachePath() (string, error) {
-	dir, err := getCacheDir()
+// migrateCache moves the cache file from the state directory to path if
+// only the former exists. Failures are ignored; the cache is just rebuilt.
+func migrateCache(path string) {
+	stateDir, err := state.Dir()
 	if err != nil {
-		return "", err
+		return
+	}
+	old := filepath.Join(stateDir, cacheFileName)
+	if old == path {
+		return
+	}
+	if _, err := os.Stat(old); err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err == nil {
+		os.Remove(old)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	if err := os.Rename(old, path); err != nil {
+		os.Remove(old)
 	}
-	return filepath.Join(dir, cacheFileName), nil
 }
 
-// loadCache loads the cached update check from disk.
-func loadCache() (*CacheEntry, error) {
-	path, err := getCachePath()
+// LoadCache loads the cached update check from disk.
+func LoadCache() (*CacheEntry, error) {
+	path, err := CachePath()
 	if err != nil {
 		return nil, err
 	}
@@ -76,8 +113,8 @@ func loadCache() (*CacheEntry, error) {
 	return &cache, nil
 }
 
-// saveCache saves the update info to cache.
-func saveCache(info *Info) error {
+// saveCache saves the update info checked on channel to cache.
+func saveCache(info *Info, channel Channel) error {
 	dir, err := getCacheDir()
 	if err != nil {
 		return err
@@ -93,7 +130,9 @@ func saveCache(info *Info) error {
 		LatestVersion:   info.LatestVersion,
 		UpdateAvailable: info.UpdateAvailable,
 		DownloadURL:     info.DownloadURL,
+		ChecksumURL:     info.ChecksumURL,
 		ReleaseURL:      info.ReleaseURL,
+		Channel:         channel,
 	}
 
 	data, err := json.MarshalIndent(cache, "", "  ")
@@ -101,19 +140,47 @@ func saveCache(info *Info) error {
 		return err
 	}
 
-	path, err := getCachePath()
+	path, err := CachePath()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	// The check runs in the background and may be abandoned when wk exits,
+	// so write a temporary file and rename it; a reader never sees half a
+	// cache
+	tmp, err := os.CreateTemp(dir, cacheFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
 }
 
-// isValid checks if the cache entry is still valid.
-func (c *CacheEntry) isValid(currentVersion string) bool {
+// isValid checks if the cache entry is still valid for channel.
+func (c *CacheEntry) isValid(currentVersion string, channel Channel) bool {
 	if c.CurrentVersion != currentVersion {
 		return false
 	}
+	// Entries written before channels existed were stable checks
+	if c.Channel != channel && !(c.Channel == "" && channel == ChannelStable) {
+		return false
+	}
 	return time.Since(c.CheckedAt) < cacheTTL
 }
 
@@ -124,13 +191,14 @@ func (c *CacheEntry) toInfo() *Info {
 		LatestVersion:   c.LatestVersion,
 		UpdateAvailable: c.UpdateAvailable,
 		DownloadURL:     c.DownloadURL,
+		ChecksumURL:     c.ChecksumURL,
 		ReleaseURL:      c.ReleaseURL,
 	}
 }
 
 // InvalidateCache removes the cache file.
 func InvalidateCache() error {
-	path, err := getCachePath()
+	path, err := CachePath()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,41 @@
+package updater
+
+import (
+	"os"
+	"testing"
+
+	"github.com/lucas-stellet/wk/internal/state"
+)
+
+func TestSaveCacheReplacesCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(state.DirEnv, dir)
+
+	for _, latest := range []string{"1.2.0", "1.3.0"} {
+		info := &Info{CurrentVersion: "1.1.0", LatestVersion: latest, UpdateAvailable: true}
+		if err := saveCache(info, ChannelStable); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache, err := LoadCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cache.isValid("1.1.0", ChannelStable) || cache.LatestVersion != "1.3.0" {
+		t.Errorf("cache = %+v, want a valid entry for 1.3.0", cache)
+	}
+
+	// Only the cache itself is left, no temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != cacheFileName {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("cache dir holds %v, want only %s", names, cacheFileName)
+	}
+}
@@ -0,0 +1,57 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-stellet/wk/internal/state"
+)
+
+const channelFileName = "update-channel"
+
+// Channel selects which releases are considered when checking for updates.
+type Channel string
+
+const (
+	// ChannelStable only considers the latest full release.
+	ChannelStable Channel = "stable"
+	// ChannelPrerelease also considers release candidates and betas.
+	ChannelPrerelease Channel = "prerelease"
+)
+
+// LoadChannel returns the persisted update channel, defaulting to stable.
+func LoadChannel() Channel {
+	dir, err := state.Dir()
+	if err != nil {
+		return ChannelStable
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, channelFileName))
+	if err != nil {
+		return ChannelStable
+	}
+
+	if Channel(strings.TrimSpace(string(data))) == ChannelPrerelease {
+		return ChannelPrerelease
+	}
+	return ChannelStable
+}
+
+// SaveChannel persists channel so later update checks use it.
+func SaveChannel(channel Channel) error {
+	dir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, channelFileName), []byte(string(channel)+"\n"), 0644); err != nil {
+		return fmt.Errorf("save update channel: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package updater
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiAttempts is how many times an API request is tried.
+	apiAttempts = 2
+	// apiRetryDelay is the wait before retrying when the server doesn't
+	// send Retry-After.
+	apiRetryDelay = time.Second
+	// apiMaxRetryAfter is the longest Retry-After wk waits for; longer
+	// waits (e.g. an exhausted hourly rate limit) fail right away.
+	apiMaxRetryAfter = 10 * time.Second
+)
+
+// userAgent identifies wk to GitHub, which rejects requests without one.
+const userAgent = repoOwner + "/" + repoName
+
+// tokenEnvs are the environment variables checked, in order, for a GitHub
+// token. Authenticated requests get a much higher rate limit than the 60
+// per hour per IP allowed otherwise, which shared CI runners exhaust.
+var tokenEnvs = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+// apiClient is the client for GitHub API requests. The timeout keeps a
+// flaky network from hanging the commands that check for updates.
+var apiClient = &http.Client{Timeout: 5 * time.Second}
+
+// checksumClient fetches release checksum files, which are small.
+var checksumClient = &http.Client{Timeout: 30 * time.Second}
+
+// downloadClient downloads release archives. The timeout is generous so
+// slow connections can finish, but a stalled download still ends.
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// getFile fetches url, a release asset, with client. The caller must close
+// the response body.
+func getFile(client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return client.Do(req)
+}
+
+// getAPI fetches url from the GitHub API, authenticated with the token from
+// tokenEnvs if one is set. Network errors, server errors and rate limiting
+// (429, or 403 with Retry-After) are retried, honoring Retry-After. The last
+// response is returned whatever its status; the caller must close its body.
+func getAPI(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", userAgent)
+	if token := githubToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	delay := apiRetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := apiClient.Do(req)
+		if (err == nil && !shouldRetry(resp)) || attempt == apiAttempts {
+			return resp, err
+		}
+
+		if resp != nil {
+			if d, ok := retryAfter(resp); ok {
+				if d > apiMaxRetryAfter {
+					return resp, nil
+				}
+				delay = d
+			}
+			resp.Body.Close()
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// githubToken returns the first token set in tokenEnvs, or "".
+func githubToken() string {
+	for _, env := range tokenEnvs {
+		if token := strings.TrimSpace(os.Getenv(env)); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// shouldRetry reports whether resp is a failure that may succeed when
+// retried.
+func shouldRetry(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		// GitHub signals secondary rate limits with 403 and Retry-After
+		_, ok := retryAfter(resp)
+		return ok
+	}
+	return false
+}
+
+// retryAfter returns the wait requested by resp's Retry-After header, given
+// in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(t)), true
+	}
+	return 0, false
+}
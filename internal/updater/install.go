@@ -2,13 +2,18 @@ package updater
 
 import (
 	"archive/tar"
+	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
@@ -69,20 +74,19 @@ func isHomebrewInstall(execPath string) bool {
 	return false
 }
 
-// PerformUpdate downloads and installs the new version.
+// PerformUpdate downloads and installs the new version. Returns ErrOffline
+// in offline mode.
 func PerformUpdate(info *Info) error {
+	if Offline() {
+		return ErrOffline
+	}
 	if info.DownloadURL == "" {
 		return fmt.Errorf("no download URL available for your platform")
 	}
 
-	execPath, err := os.Executable()
+	execPath, err := executablePath()
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve symlinks: %w", err)
+		return err
 	}
 
 	// Check if we need elevated permissions
@@ -97,16 +101,27 @@ func PerformUpdate(info *Info) error {
 
 	// Download the archive
 	archivePath := filepath.Join(tmpDir, "wk.tar.gz")
-	if err := downloadFile(info.DownloadURL, archivePath); err != nil {
+	archiveSum, err := downloadFile(info.DownloadURL, archivePath)
+	if err != nil {
 		return fmt.Errorf("failed to download update: %w", err)
 	}
 
+	// Verify the archive against the release checksums
+	if err := verifyChecksum(info, archiveSum); err != nil {
+		return err
+	}
+
 	// Extract the binary
 	newBinaryPath := filepath.Join(tmpDir, "wk")
 	if err := extractBinary(archivePath, newBinaryPath); err != nil {
 		return fmt.Errorf("failed to extract update: %w", err)
 	}
 
+	// Keep the current binary so the update can be rolled back
+	if err := saveBackup(execPath, info.CurrentVersion); err != nil {
+		return fmt.Errorf("failed to back up current binary: %w", err)
+	}
+
 	// Install the binary (with sudo if needed)
 	if needsSudo {
 		return installWithSudo(newBinaryPath, execPath)
@@ -115,6 +130,20 @@ func PerformUpdate(info *Info) error {
 	return installDirect(newBinaryPath, execPath)
 }
 
+// executablePath returns the resolved path of the running wk binary.
+func executablePath() (string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	execPath, err = filepath.EvalSymlinks(execPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlinks: %w", err)
+	}
+	return execPath, nil
+}
+
 // requiresElevatedPermissions checks if the target directory needs sudo.
 func requiresElevatedPermissions(execPath string) bool {
 	dir := filepath.Dir(execPath)
@@ -130,8 +159,13 @@ func requiresElevatedPermissions(execPath string) bool {
 	return false
 }
 
-// installWithSudo installs the binary using sudo.
+// installWithSudo installs the binary using sudo. Windows has no sudo, so
+// there the update must be run from an elevated prompt instead.
 func installWithSudo(newBinaryPath, execPath string) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("cannot write to %s; run 'wk update' from an elevated (Administrator) prompt", filepath.Dir(execPath))
+	}
+
 	backupPath := execPath + ".backup"
 
 	fmt.Println("Elevated permissions required. Using sudo...")
@@ -223,25 +257,82 @@ func installDirect(newBinaryPath, execPath string) error {
 }
 
 // downloadFile downloads a file from URL to the specified path.
-func downloadFile(url, destPath string) error {
-	resp, err := http.Get(url)
+// Returns the hex-encoded SHA256 of the downloaded content.
+func downloadFile(url, destPath string) (string, error) {
+	resp, err := getFile(downloadClient, url)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
 	}
 
 	out, err := os.Create(destPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	return err
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyChecksum checks sum against the entry for the downloaded archive in
+// the release's checksums file.
+func verifyChecksum(info *Info, sum string) error {
+	if info.ChecksumURL == "" {
+		return fmt.Errorf("no %s found in release; refusing to install unverified binary", checksumsAsset)
+	}
+
+	checksums, err := fetchChecksums(info.ChecksumURL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksums: %w", err)
+	}
+
+	name := path.Base(info.DownloadURL)
+	expected, ok := checksums[name]
+	if !ok {
+		return fmt.Errorf("no checksum for %s in %s", name, checksumsAsset)
+	}
+	if !strings.EqualFold(expected, sum) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, sum)
+	}
+	return nil
+}
+
+// fetchChecksums downloads a checksums file and returns checksums by filename.
+// Each line has the form "<sha256>  <filename>".
+func fetchChecksums(url string) (map[string]string, error) {
+	resp, err := getFile(checksumClient, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	return parseChecksums(resp.Body)
+}
+
+// parseChecksums parses sha256sum-style lines into checksums by filename.
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	return checksums, scanner.Err()
 }
 
 // extractBinary extracts the wk binary from a tar.gz archive.
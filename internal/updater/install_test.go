@@ -0,0 +1,44 @@
+package updater
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchChecksums(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != userAgent {
+			t.Errorf("User-Agent = %q, want %q", ua, userAgent)
+		}
+		fmt.Fprintln(w, "abc123  wk_linux_amd64.tar.gz")
+	}))
+	defer srv.Close()
+
+	checksums, err := fetchChecksums(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := checksums["wk_linux_amd64.tar.gz"]; got != "abc123" {
+		t.Errorf("checksum = %q, want %q", got, "abc123")
+	}
+}
+
+func TestFetchChecksumsTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	saved := checksumClient
+	checksumClient = &http.Client{Timeout: 50 * time.Millisecond}
+	t.Cleanup(func() { checksumClient = saved })
+
+	if _, err := fetchChecksums(srv.URL); err == nil {
+		t.Error("fetchChecksums from a stalled server succeeded, want a timeout")
+	}
+}
@@ -0,0 +1,34 @@
+package updater
+
+import (
+	"errors"
+	"os"
+)
+
+// OfflineEnv turns on offline mode when set to anything but "", "0" or
+// "false".
+const OfflineEnv = "WK_OFFLINE"
+
+// offline is set by SetOffline, from the global --offline flag.
+var offline bool
+
+// ErrOffline is returned instead of making network requests in offline mode.
+var ErrOffline = errors.New("offline mode: network access is disabled (unset " + OfflineEnv + " or drop --offline)")
+
+// SetOffline turns offline mode on, in addition to OfflineEnv.
+func SetOffline(on bool) {
+	offline = on
+}
+
+// Offline reports whether offline mode is on, so that nothing is fetched
+// over the network: not the update check, nor an update.
+func Offline() bool {
+	if offline {
+		return true
+	}
+	switch os.Getenv(OfflineEnv) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
@@ -6,29 +6,37 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
 const (
-	repoOwner = "lucas-stellet"
-	repoName  = "wk"
-	apiURL    = "https://api.github.com/repos/" + repoOwner + "/" + repoName + "/releases/latest"
+	repoOwner   = "lucas-stellet"
+	repoName    = "wk"
+	releasesURL = "https://api.github.com/repos/" + repoOwner + "/" + repoName + "/releases"
+	apiURL      = releasesURL + "/latest"
 )
 
+// checksumsAsset is the checksum file GoReleaser publishes with each release.
+const checksumsAsset = "checksums.txt"
+
 // Info contains version and update information.
 type Info struct {
 	CurrentVersion  string
 	LatestVersion   string
 	UpdateAvailable bool
 	DownloadURL     string
+	ChecksumURL     string
 	ReleaseURL      string
 }
 
 // githubRelease represents the GitHub API response for a release.
 type githubRelease struct {
-	TagName string  `json:"tag_name"`
-	HTMLURL string  `json:"html_url"`
-	Assets  []asset `json:"assets"`
+	TagName    string  `json:"tag_name"`
+	HTMLURL    string  `json:"html_url"`
+	Draft      bool    `json:"draft"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []asset `json:"assets"`
 }
 
 type asset struct {
@@ -36,9 +44,20 @@ type asset struct {
 	BrowserDownloadURL string `json:"browser_download_url"`
 }
 
-// CheckForUpdate queries GitHub API for the latest release and compares versions.
-func CheckForUpdate(currentVersion string) (*Info, error) {
-	release, err := fetchLatestRelease()
+// CheckForUpdate queries GitHub API for the latest release on channel and
+// compares versions. Returns ErrOffline in offline mode.
+func CheckForUpdate(currentVersion string, channel Channel) (*Info, error) {
+	if Offline() {
+		return nil, ErrOffline
+	}
+
+	var release *githubRelease
+	var err error
+	if channel == ChannelPrerelease {
+		release, err = fetchNewestRelease()
+	} else {
+		release, err = fetchLatestRelease()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +71,7 @@ func CheckForUpdate(currentVersion string) (*Info, error) {
 
 	if info.UpdateAvailable {
 		info.DownloadURL = findDownloadURL(release.Assets)
+		info.ChecksumURL = findChecksumURL(release.Assets)
 	}
 
 	return info, nil
@@ -59,7 +79,7 @@ func CheckForUpdate(currentVersion string) (*Info, error) {
 
 // fetchLatestRelease fetches the latest release from GitHub API.
 func fetchLatestRelease() (*githubRelease, error) {
-	resp, err := http.Get(apiURL)
+	resp, err := getAPI(apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch release: %w", err)
 	}
@@ -77,6 +97,40 @@ func fetchLatestRelease() (*githubRelease, error) {
 	return &release, nil
 }
 
+// fetchNewestRelease fetches recent releases from GitHub API and returns the
+// one with the highest version, including pre-releases.
+func fetchNewestRelease() (*githubRelease, error) {
+	resp, err := getAPI(releasesURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var newest *githubRelease
+	for i := range releases {
+		r := &releases[i]
+		if r.Draft {
+			continue
+		}
+		if newest == nil || compareVersions(r.TagName, newest.TagName) > 0 {
+			newest = r
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no releases found")
+	}
+	return newest, nil
+}
+
 // isNewerVersion compares two version strings and returns true if latest is newer.
 func isNewerVersion(current, latest string) bool {
 	current = strings.TrimPrefix(current, "v")
@@ -89,29 +143,87 @@ func isNewerVersion(current, latest string) bool {
 	return latest != current && compareVersions(latest, current) > 0
 }
 
-// compareVersions compares two semver strings.
+// compareVersions compares two semver strings following semver precedence:
+// major, minor and patch are compared numerically, a version with a
+// pre-release (e.g. "-rc.1") is lower than the same version without one, and
+// build metadata after "+" is ignored.
 // Returns: 1 if a > b, -1 if a < b, 0 if equal.
 func compareVersions(a, b string) int {
-	partsA := strings.Split(a, ".")
-	partsB := strings.Split(b, ".")
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
 
-	for i := 0; i < 3; i++ {
-		var numA, numB int
-		if i < len(partsA) {
-			fmt.Sscanf(partsA[i], "%d", &numA)
-		}
-		if i < len(partsB) {
-			fmt.Sscanf(partsB[i], "%d", &numB)
+	for i := range coreA {
+		if c := compareInts(coreA[i], coreB[i]); c != 0 {
+			return c
 		}
+	}
+
+	return comparePrerelease(preA, preB)
+}
+
+// splitVersion parses v into its major, minor and patch numbers and its
+// pre-release identifiers. Missing or malformed numbers count as 0.
+func splitVersion(v string) ([3]int, []string) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, hasPre := strings.Cut(v, "-")
+
+	var core [3]int
+	for i, part := range strings.SplitN(v, ".", 3) {
+		core[i], _ = strconv.Atoi(part)
+	}
+
+	if !hasPre {
+		return core, nil
+	}
+	return core, strings.Split(pre, ".")
+}
+
+// comparePrerelease orders pre-release identifiers per semver: no
+// pre-release ranks highest; numeric identifiers compare numerically and rank
+// below alphanumeric ones, which compare lexically; a longer list wins when
+// all shared identifiers are equal.
+func comparePrerelease(a, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
 
-		if numA > numB {
-			return 1
+	for i := 0; i < len(a) && i < len(b); i++ {
+		numA, errA := strconv.Atoi(a[i])
+		numB, errB := strconv.Atoi(b[i])
+
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareInts(numA, numB)
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(a[i], b[i])
 		}
-		if numA < numB {
-			return -1
+		if c != 0 {
+			return c
 		}
 	}
 
+	return compareInts(len(a), len(b))
+}
+
+// compareInts returns 1 if a > b, -1 if a < b, 0 if equal.
+func compareInts(a, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
 	return 0
 }
 
@@ -139,6 +251,16 @@ func findDownloadURL(assets []asset) string {
 	return ""
 }
 
+// findChecksumURL finds the URL of the release's checksums file.
+func findChecksumURL(assets []asset) string {
+	for _, a := range assets {
+		if a.Name == checksumsAsset {
+			return a.BrowserDownloadURL
+		}
+	}
+	return ""
+}
+
 // GetAssetFilename returns the expected asset filename for the current platform.
 func GetAssetFilename(version string) string {
 	version = strings.TrimPrefix(version, "v")
@@ -7,18 +7,39 @@ import (
 	"os/exec"
 
 	"github.com/lucas-stellet/wk/internal/config"
+	"github.com/lucas-stellet/wk/internal/exit"
+	"github.com/lucas-stellet/wk/internal/output"
+	"github.com/lucas-stellet/wk/internal/worktree"
 	"github.com/spf13/cobra"
 )
 
-// IsGitRepository reports whether the current directory is inside a git repository.
-func IsGitRepository() bool {
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
-	err := cmd.Run()
-	return err == nil
+// SkipGitValidation is the command annotation that opts a command (and its
+// subcommands) out of pre-command validation:
+//
+//	Annotations: map[string]string{validate.SkipGitValidation: "true"}
+const SkipGitValidation = "skip-git-validation"
+
+// IsGitRepository checks that the git binary (see worktree.GitBin) is
+// executable and that the current directory is inside a git repository.
+func IsGitRepository() error {
+	bin := worktree.GitBin()
+	if _, err := exec.LookPath(bin); err != nil {
+		if os.Getenv(worktree.GitBinEnv) != "" {
+			return exit.WithCode(exit.NotGitRepository, fmt.Errorf("git binary %q from %s is not executable: %w", bin, worktree.GitBinEnv, err))
+		}
+		return exit.WithCode(exit.NotGitRepository, fmt.Errorf("git not found in PATH\n\nInstall git or set %s to the git binary to use", worktree.GitBinEnv))
+	}
+
+	cmd := exec.Command(bin, "rev-parse", "--is-inside-work-tree")
+	if err := cmd.Run(); err != nil {
+		return exit.WithCode(exit.NotGitRepository, fmt.Errorf("not a git repository (or any parent up to mount point /)\n\nRun this command from inside a git repository"))
+	}
+	return nil
 }
 
 // CheckConfig validates the existence and format of .wk.yaml.
-// Returns exists=true if file found, valid=true if YAML parses correctly.
+// Returns exists=true if file found, valid=true if YAML parses correctly and
+// has no unknown fields or values of the wrong type.
 func CheckConfig() (exists, valid bool, err error) {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -42,7 +63,7 @@ func CheckConfig() (exists, valid bool, err error) {
 }
 
 // RunPreValidation performs validation checks before command execution.
-// It skips validation for help, version, and update commands.
+// It skips validation for help and for commands annotated with SkipGitValidation.
 func RunPreValidation(cmd *cobra.Command) error {
 	if isHelpCommand(cmd) {
 		return nil
@@ -53,8 +74,8 @@ func RunPreValidation(cmd *cobra.Command) error {
 		return nil
 	}
 
-	if !IsGitRepository() {
-		return fmt.Errorf("not a git repository (or any parent up to mount point /)\n\nRun this command from inside a git repository")
+	if err := IsGitRepository(); err != nil {
+		return err
 	}
 
 	if cmd.Name() == "init" {
@@ -63,13 +84,17 @@ func RunPreValidation(cmd *cobra.Command) error {
 
 	exists, valid, err := CheckConfig()
 
+	if !exists && err != nil {
+		return exit.WithCode(exit.InvalidConfig, err)
+	}
+
 	if !exists {
-		fmt.Fprintln(os.Stderr, "hint: no .wk.yaml found. Run 'wk init' to create one.\n")
+		output.Hintf("no .wk.yaml found. Run 'wk init' to create one.\n\n")
 		return nil
 	}
 
 	if !valid {
-		return fmt.Errorf("invalid .wk.yaml: %w\n\nFix the YAML syntax in your configuration file", err)
+		return exit.WithCode(exit.InvalidConfig, fmt.Errorf("invalid .wk.yaml:\n%w\n\nFix the YAML syntax, misspelled keys or wrong types in your configuration file", err))
 	}
 
 	return nil
@@ -89,12 +114,11 @@ func isHelpCommand(cmd *cobra.Command) bool {
 	return false
 }
 
-// shouldSkipValidation returns true for commands that don't need git repo validation.
+// shouldSkipValidation reports whether cmd or one of its parents is annotated
+// with SkipGitValidation.
 func shouldSkipValidation(cmd *cobra.Command) bool {
-	skipCommands := []string{"version", "update", "completion"}
-	name := cmd.Name()
-	for _, skip := range skipCommands {
-		if name == skip {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[SkipGitValidation] == "true" {
 			return true
 		}
 	}
@@ -0,0 +1,86 @@
+package worktree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucas-stellet/wk/internal/exit"
+)
+
+// FindByBranchFuzzy returns the worktree whose branch is query. Failing that,
+// it returns the branches of the worktrees that resemble query, best first:
+// those containing it, then those containing its characters in order. Case
+// and the separators - _ / . are ignored, so "feat-login" matches
+// "feature/login". Returns a not found error if nothing resembles query.
+func FindByBranchFuzzy(query string) (*Worktree, []string, error) {
+	worktrees, err := List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, wt := range worktrees {
+		if hasBranch(wt) && wt.Branch == query {
+			return &worktrees[i], nil, nil
+		}
+	}
+
+	matches := fuzzyMatches(query, worktreeBranchNames(worktrees))
+	if len(matches) == 0 {
+		return nil, nil, exit.WithCode(exit.NotFound, fmt.Errorf("worktree for branch '%s' not found", query))
+	}
+	return nil, matches, nil
+}
+
+// fuzzyMatches returns the branches that resemble query, best first, as
+// described for FindByBranchFuzzy.
+func fuzzyMatches(query string, branches []string) []string {
+	q := fuzzyKey(query)
+	if q == "" {
+		return nil
+	}
+
+	var substrings, subsequences []string
+	for _, branch := range branches {
+		key := fuzzyKey(branch)
+		switch {
+		case strings.Contains(key, q):
+			substrings = append(substrings, branch)
+		case isSubsequence(q, key):
+			subsequences = append(subsequences, branch)
+		}
+	}
+
+	// Shorter branches have fewer characters besides the query's
+	byLength := func(s []string) {
+		sort.SliceStable(s, func(i, j int) bool { return len(s[i]) < len(s[j]) })
+	}
+	byLength(substrings)
+	byLength(subsequences)
+
+	return append(substrings, subsequences...)
+}
+
+// fuzzyKey returns s lowercased, without the separators - _ / and .
+func fuzzyKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', '/', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(s))
+}
+
+// isSubsequence reports whether the characters of sub appear in s in order.
+func isSubsequence(sub, s string) bool {
+	for _, r := range s {
+		if sub == "" {
+			break
+		}
+		if strings.HasPrefix(sub, string(r)) {
+			sub = sub[len(string(r)):]
+		}
+	}
+	return sub == ""
+}
@@ -0,0 +1,47 @@
+package worktree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFuzzyMatches(t *testing.T) {
+	branches := []string{"main", "feature/login-page", "feature/login", "fix/typo", "feat-y", "refactor/fly", "docs/fyi"}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Substring matches come first, shortest first
+		{"login", []string{"feature/login", "feature/login-page"}},
+		// Separators and case are ignored
+		{"FEATURE-LOGIN", []string{"feature/login", "feature/login-page"}},
+		// Subsequence matches follow substring matches
+		{"fy", []string{"docs/fyi", "feat-y", "fix/typo", "refactor/fly"}},
+		{"featy", []string{"feat-y"}},
+		{"zzz", nil},
+		{"--", nil},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatches(tt.query, branches); !slices.Equal(got, tt.want) {
+			t.Errorf("fuzzyMatches(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIsSubsequence(t *testing.T) {
+	tests := []struct {
+		sub, s string
+		want   bool
+	}{
+		{"", "abc", true},
+		{"ac", "abc", true},
+		{"ca", "abc", false},
+		{"abcd", "abc", false},
+	}
+	for _, tt := range tests {
+		if got := isSubsequence(tt.sub, tt.s); got != tt.want {
+			t.Errorf("isSubsequence(%q, %q) = %v, want %v", tt.sub, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package worktree
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lucas-stellet/wk/internal/debuglog"
+	"github.com/lucas-stellet/wk/internal/output"
+)
+
+// GitBinEnv is the environment variable that overrides the git binary.
+const GitBinEnv = "WK_GIT_BIN"
+
+// GitBin returns the git binary to run: $WK_GIT_BIN if set, otherwise "git"
+// looked up on PATH.
+func GitBin() string {
+	if bin := os.Getenv(GitBinEnv); bin != "" {
+		return bin
+	}
+	return "git"
+}
+
+// gitError is returned when a git command fails. Its message includes the
+// full command line and git's trimmed output.
+type gitError struct {
+	args   []string
+	output string
+	err    error
+}
+
+func (e *gitError) Error() string {
+	msg := output.FormatCommand(append([]string{GitBin()}, e.args...)) + " failed"
+	if e.output != "" {
+		return msg + ": " + e.output
+	}
+	return msg + ": " + e.err.Error()
+}
+
+func (e *gitError) Unwrap() error {
+	return e.err
+}
+
+// runGit runs git with args in the current directory and returns its stdout.
+func runGit(args ...string) ([]byte, error) {
+	return runGitIn("", args...)
+}
+
+// runGitIn runs git with args in dir and returns its stdout. On failure the
+// error includes the command line and git's stderr and stdout.
+func runGitIn(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command(GitBin(), args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), SkipSetupHookEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	output.Command(cmd)
+	start := time.Now()
+	err := cmd.Run()
+	debuglog.Command(cmd, time.Since(start), err, stdout.Bytes(), stderr.Bytes())
+	if err != nil {
+		out := strings.TrimSpace(stderr.String() + "\n" + stdout.String())
+		return stdout.Bytes(), &gitError{args: args, output: out, err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// gitOutputIn runs git with args in dir and returns its trimmed stdout.
+func gitOutputIn(dir string, args ...string) (string, error) {
+	out, err := runGitIn(dir, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitOutput runs git with args and returns its trimmed stdout.
+func gitOutput(args ...string) (string, error) {
+	return gitOutputIn("", args...)
+}
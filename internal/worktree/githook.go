@@ -0,0 +1,106 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SkipSetupHookEnv is set for every git command wk runs, so the hook
+// installed by InstallSetupHook doesn't run 'wk setup' for worktrees that wk
+// creates and sets up itself.
+const SkipSetupHookEnv = "WK_SKIP_SETUP_HOOK"
+
+const (
+	setupHookName  = "post-checkout"
+	setupHookBegin = "# >>> wk setup >>>"
+	setupHookEnd   = "# <<< wk setup <<<"
+)
+
+// setupHookBlock runs 'wk setup' after 'git worktree add', which is the only
+// checkout whose previous HEAD is the null object id.
+const setupHookBlock = setupHookBegin + `
+# Set up worktrees created outside wk; installed by 'wk install-hooks'
+if [ -z "$` + SkipSetupHookEnv + `" ] && [ -z "$(printf '%s' "$1" | tr -d 0)" ] && command -v wk >/dev/null 2>&1; then
+  wk setup || echo "wk setup failed; run it again in $(pwd)" >&2
+fi
+` + setupHookEnd + "\n"
+
+// SetupHookPath returns the path of the post-checkout hook shared by all
+// worktrees of the repository, honoring core.hooksPath.
+func SetupHookPath() (string, error) {
+	dir, err := gitOutput("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, setupHookName), nil
+}
+
+// InstallSetupHook makes the post-checkout hook run 'wk setup' in worktrees
+// created by 'git worktree add'. An existing shell hook is appended to, not
+// replaced. Returns the hook's path and whether it was changed; installing
+// twice changes nothing.
+func InstallSetupHook() (path string, changed bool, err error) {
+	path, err = SetupHookPath()
+	if err != nil {
+		return "", false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return path, false, err
+		}
+		return path, true, os.WriteFile(path, []byte("#!/bin/sh\n\n"+setupHookBlock), 0755)
+	}
+	if err != nil {
+		return path, false, err
+	}
+
+	hook := string(data)
+	if strings.Contains(hook, setupHookBegin) {
+		return path, false, nil
+	}
+	if !isShellScript(hook) {
+		return path, false, fmt.Errorf("%s is not a shell script; add 'wk setup' to it manually", path)
+	}
+
+	if !strings.HasSuffix(hook, "\n") {
+		hook += "\n"
+	}
+	hook += "\n" + setupHookBlock
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return path, false, err
+	}
+	return path, true, os.WriteFile(path, []byte(hook), info.Mode().Perm())
+}
+
+// isShellScript reports whether a hook's shebang runs a POSIX-like shell, so
+// the wk block can be appended to it.
+func isShellScript(hook string) bool {
+	line, _, _ := strings.Cut(hook, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return false
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	switch interpreter {
+	case "sh", "bash", "zsh", "dash", "ksh":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a repository with one commit in a temporary directory,
+// makes it the working directory and returns its path. git's global and
+// system configuration are ignored.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "wk")
+	t.Setenv("GIT_AUTHOR_EMAIL", "wk@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "wk")
+	t.Setenv("GIT_COMMITTER_EMAIL", "wk@example.com")
+	t.Setenv(GitBinEnv, "")
+	SetRemote("")
+	t.Cleanup(func() { SetRemote("") })
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir = filepath.Join(dir, "repo")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	git(t, "init", "--quiet", "--initial-branch=main")
+	git(t, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	return dir
+}
+
+// git runs git with args in the working directory and fails the test if it
+// fails.
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// countGit makes wk run git through a wrapper that logs each invocation, and
+// returns a function reporting how many logged invocations start with args.
+func countGit(t *testing.T) func(args ...string) int {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "git.log")
+	script := filepath.Join(dir, "git")
+	wrapper := "#!/bin/sh\necho \"$*\" >> " + log + "\nexec git \"$@\"\n"
+	if err := os.WriteFile(script, []byte(wrapper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(GitBinEnv, script)
+
+	return func(args ...string) int {
+		data, err := os.ReadFile(log)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		prefix := strings.Join(args, " ")
+		n := 0
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" && strings.HasPrefix(line, prefix) {
+				n++
+			}
+		}
+		return n
+	}
+}
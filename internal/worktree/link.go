@@ -0,0 +1,72 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VerifyLink checks that the linked worktree at path shares this
+// repository's object store: its .git file must point to an admin dir under
+// the main repository's worktrees/ directory, and that admin dir must point
+// back to path. A broken link means git would treat the worktree as a
+// separate, disconnected repository.
+func VerifyLink(path string) error {
+	commonDir, err := gitOutputIn("", "rev-parse", "--git-common-dir")
+	if err != nil {
+		return err
+	}
+	commonDir, err = realPath(commonDir)
+	if err != nil {
+		return fmt.Errorf("resolve git common dir: %w", err)
+	}
+
+	dotGit := filepath.Join(path, ".git")
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return fmt.Errorf("worktree %s is not linked: %w", path, err)
+	}
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, "gitdir: ") {
+		return fmt.Errorf("worktree %s is not linked: %s has no gitdir line", path, dotGit)
+	}
+
+	adminDir := strings.TrimPrefix(content, "gitdir: ")
+	if !filepath.IsAbs(adminDir) {
+		adminDir = filepath.Join(path, adminDir)
+	}
+	adminDir, err = realPath(adminDir)
+	if err != nil {
+		return fmt.Errorf("worktree %s points to missing admin dir: %w", path, err)
+	}
+	if filepath.Dir(adminDir) != filepath.Join(commonDir, "worktrees") {
+		return fmt.Errorf("worktree %s points to %s, outside this repository's %s", path, adminDir, commonDir)
+	}
+
+	data, err = os.ReadFile(filepath.Join(adminDir, "gitdir"))
+	if err != nil {
+		return fmt.Errorf("worktree %s admin dir is incomplete: %w", path, err)
+	}
+	backLink, err := realPath(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("worktree %s admin dir points to missing %s", path, strings.TrimSpace(string(data)))
+	}
+	wantBackLink, err := realPath(dotGit)
+	if err != nil {
+		return err
+	}
+	if backLink != wantBackLink {
+		return fmt.Errorf("worktree %s is registered as %s; run 'git worktree repair'", path, filepath.Dir(backLink))
+	}
+	return nil
+}
+
+// realPath returns the absolute path of p with symlinks resolved.
+func realPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
@@ -0,0 +1,106 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isCrossDeviceError reports whether git output describes a failed rename
+// across filesystems (EXDEV).
+func isCrossDeviceError(output string) bool {
+	return strings.Contains(strings.ToLower(output), "cross-device link")
+}
+
+// moveAcrossDevices moves a worktree to a path on another filesystem by
+// copying it, re-registering the copy with git worktree repair, and verifying
+// HEAD and status match before deleting the original.
+func moveAcrossDevices(oldPath, newPath string) error {
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("destination %s already exists", newPath)
+	}
+
+	headBefore, err := gitOutputIn(oldPath, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	statusBefore, err := gitOutputIn(oldPath, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+
+	if err := copyTree(oldPath, newPath); err != nil {
+		os.RemoveAll(newPath)
+		return fmt.Errorf("copy worktree to %s: %w", newPath, err)
+	}
+
+	// Point git's admin entry for this worktree at the copy
+	if _, err := gitOutputIn(newPath, "worktree", "repair"); err != nil {
+		os.RemoveAll(newPath)
+		return fmt.Errorf("register copied worktree: %w", err)
+	}
+
+	headAfter, errHead := gitOutputIn(newPath, "rev-parse", "HEAD")
+	statusAfter, errStatus := gitOutputIn(newPath, "status", "--porcelain")
+	if errHead != nil || errStatus != nil || headAfter != headBefore || statusAfter != statusBefore {
+		// Restore registration to the original and discard the copy
+		gitOutputIn(oldPath, "worktree", "repair")
+		os.RemoveAll(newPath)
+		return fmt.Errorf("copied worktree at %s does not match the original; original left in place", newPath)
+	}
+
+	if err := os.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("worktree copied to %s but failed to remove original: %w", newPath, err)
+	}
+	return nil
+}
+
+// copyTree recursively copies src to dst, preserving modes and symlinks.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		default:
+			return copyRegularFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+// copyRegularFile copies a single file from src to dst with the given mode.
+func copyRegularFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,169 @@
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultPathTemplate places each worktree directly under the worktrees dir,
+// named after its branch.
+const DefaultPathTemplate = "{{.Branch}}"
+
+// configuredPathTemplate is the template set via SetPathTemplate.
+var configuredPathTemplate string
+
+// SetPathTemplate sets the Go template used to compute a worktree's path
+// relative to the worktrees dir. An empty template restores the default.
+func SetPathTemplate(tmpl string) {
+	configuredPathTemplate = tmpl
+}
+
+// flatBranchDirs is set via SetBranchDirStyle.
+var flatBranchDirs bool
+
+// SetBranchDirStyle sets how branch names with slashes map to directories:
+// "flat" replaces the slashes with dashes, anything else (the default,
+// "nested") keeps one directory per segment.
+func SetBranchDirStyle(style string) {
+	flatBranchDirs = style == "flat"
+}
+
+// BranchDir returns branch as it appears in worktree paths under the
+// configured branch dir style.
+func BranchDir(branch string) string {
+	if flatBranchDirs {
+		return strings.ReplaceAll(branch, "/", "-")
+	}
+	return branch
+}
+
+// PathData is the data available to worktree path templates.
+type PathData struct {
+	Branch string // branch name as a directory, e.g. "feature/login" or "feature-login"
+	Repo   string // repository name
+	User   string // current user name
+	Year   string // e.g. "2025"
+	Month  string // e.g. "03"
+	Date   string // e.g. "2025-03-14"
+}
+
+// StandardPath returns the path a worktree for branch is created at: the
+// worktrees dir joined with the rendered path template.
+func StandardPath(branch string) (string, error) {
+	return NewRepo().StandardPath(branch)
+}
+
+// StandardPath is like the package-level StandardPath, using r's cache.
+func (r *Repo) StandardPath(branch string) (string, error) {
+	worktreesDir, err := r.WorktreesDir()
+	if err != nil {
+		return "", err
+	}
+
+	repoName, err := r.Name()
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := renderPathTemplate(configuredPathTemplate, newPathData(branch, repoName, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(worktreesDir, rel), nil
+}
+
+// CheckPath returns an error if a worktree for branch can't be put at path:
+// if path would be inside another worktree or contain one, as when branches
+// "feature" and "feature/login" both get nested directories, or if another
+// branch's worktree is registered at path, as when "feature/login" and
+// "feature-login" both get flat directories. A worktree for branch already
+// registered at path is allowed, so a missing worktree can be re-added with
+// --force. branch is "(detached)" for a detached worktree.
+func (r *Repo) CheckPath(path, branch string) error {
+	worktrees, err := r.List()
+	if err != nil {
+		return err
+	}
+
+	hint, sharedHint := "", ""
+	if flatBranchDirs {
+		sharedHint = "\n\nWith branch_dir_style: flat, branch names that differ only in '/' and '-' share a directory"
+	} else {
+		hint = "\n\nSet branch_dir_style: flat in .wk.yaml to keep branches with slashes in separate directories"
+	}
+	for _, wt := range worktrees {
+		switch {
+		case wt.Path == path:
+			if wt.Branch != branch {
+				return fmt.Errorf("%s is already the worktree for '%s'%s", path, wt.Branch, sharedHint)
+			}
+		case pathInside(wt.Path, path):
+			return fmt.Errorf("%s is inside the worktree at %s%s", path, wt.Path, hint)
+		case pathInside(path, wt.Path):
+			return fmt.Errorf("%s would contain the worktree at %s%s", path, wt.Path, hint)
+		}
+	}
+	return nil
+}
+
+// pruneEmptyDirs removes dir and then its parents while they are empty,
+// stopping at the worktrees dir, so moving or removing feature/login doesn't
+// leave an empty feature directory behind.
+func pruneEmptyDirs(dir string) {
+	worktreesDir, err := GetWorktreesDir()
+	if err != nil {
+		return
+	}
+	for dir != worktreesDir && pathInside(worktreesDir, dir) {
+		// Fails, and stops, at the first directory that isn't empty
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// newPathData builds template data for branch at time now.
+func newPathData(branch, repo string, now time.Time) PathData {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return PathData{
+		Branch: BranchDir(branch),
+		Repo:   repo,
+		User:   name,
+		Year:   now.Format("2006"),
+		Month:  now.Format("01"),
+		Date:   now.Format("2006-01-02"),
+	}
+}
+
+// renderPathTemplate renders tmpl (or the default when empty) with data and
+// checks the result is a relative path that stays inside the worktrees dir.
+func renderPathTemplate(tmpl string, data PathData) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultPathTemplate
+	}
+
+	t, err := template.New("worktree_path_template").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("worktree_path_template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("worktree_path_template: %w", err)
+	}
+
+	rel := filepath.Clean(strings.TrimSpace(buf.String()))
+	if rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("worktree_path_template: %q renders to %q, which is outside the worktrees directory", tmpl, rel)
+	}
+	return rel, nil
+}
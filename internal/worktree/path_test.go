@@ -0,0 +1,207 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setBranchDirStyle sets the branch dir style for the duration of the test.
+func setBranchDirStyle(t *testing.T, style string) {
+	t.Helper()
+	SetBranchDirStyle(style)
+	t.Cleanup(func() { SetBranchDirStyle("") })
+}
+
+func TestBranchDir(t *testing.T) {
+	tests := []struct {
+		style, branch, want string
+	}{
+		{"nested", "main", "main"},
+		{"nested", "feature/login", "feature/login"},
+		{"flat", "main", "main"},
+		{"flat", "feature/login", "feature-login"},
+		{"flat", "user/feature/login", "user-feature-login"},
+	}
+	for _, tt := range tests {
+		setBranchDirStyle(t, tt.style)
+		if got := BranchDir(tt.branch); got != tt.want {
+			t.Errorf("%s: BranchDir(%q) = %q, want %q", tt.style, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestIsInStandardLocationSlashBranches(t *testing.T) {
+	dir := newTestRepo(t)
+	worktreesDir := filepath.Join(filepath.Dir(dir), "repo.worktrees")
+	nestedPath := filepath.Join(worktreesDir, "feature", "login")
+	flatPath := filepath.Join(worktreesDir, "fix-typo")
+	git(t, "worktree", "add", "--quiet", "-b", "feature/login", nestedPath)
+	git(t, "worktree", "add", "--quiet", "-b", "fix/typo", flatPath)
+
+	tests := []struct {
+		style        string
+		nested, flat bool
+	}{
+		{"nested", true, false},
+		{"flat", false, true},
+	}
+	for _, tt := range tests {
+		setBranchDirStyle(t, tt.style)
+		if got, err := IsInStandardLocation(nestedPath); err != nil || got != tt.nested {
+			t.Errorf("%s: IsInStandardLocation(%s) = %v, %v; want %v", tt.style, nestedPath, got, err, tt.nested)
+		}
+		if got, err := IsInStandardLocation(flatPath); err != nil || got != tt.flat {
+			t.Errorf("%s: IsInStandardLocation(%s) = %v, %v; want %v", tt.style, flatPath, got, err, tt.flat)
+		}
+	}
+}
+
+func TestCheckPathSharedFlatDirectory(t *testing.T) {
+	newTestRepo(t)
+	setBranchDirStyle(t, "flat")
+
+	r := NewRepo()
+	path, err := r.AddFrom("feature/login", "HEAD", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same branch may be re-added at its own path
+	if err := r.CheckPath(path, "feature/login"); err != nil {
+		t.Errorf("CheckPath for the worktree's own branch: %v", err)
+	}
+
+	_, err = r.AddFrom("feature-login", "HEAD", true)
+	if err == nil || !strings.Contains(err.Error(), "'feature/login'") {
+		t.Fatalf("AddFrom(feature-login) = %v, want an error naming 'feature/login'", err)
+	}
+	if _, err := r.FindByBranch("feature/login"); err != nil {
+		t.Errorf("feature/login's worktree is gone: %v", err)
+	}
+}
+
+func TestCheckPathRejectsNesting(t *testing.T) {
+	tests := []struct {
+		first, second, want string
+	}{
+		{"feature", "feature/login", "is inside the worktree"},
+		{"feature/login", "feature", "would contain the worktree"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.first, func(t *testing.T) {
+			newTestRepo(t)
+			setBranchDirStyle(t, "nested")
+
+			r := NewRepo()
+			if _, err := r.AddFrom(tt.first, "HEAD", false); err != nil {
+				t.Fatal(err)
+			}
+			_, err := r.AddFrom(tt.second, "HEAD", false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("AddFrom(%s) = %v, want an error containing %q", tt.second, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovePrunesEmptyDirs(t *testing.T) {
+	dir := newTestRepo(t)
+	setBranchDirStyle(t, "nested")
+	worktreesDir := filepath.Join(filepath.Dir(dir), "repo.worktrees")
+
+	for _, branch := range []string{"feature/login", "fix/deep/typo", "fix/other"} {
+		if _, err := AddFrom(branch, "HEAD", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, branch := range []string{"feature/login", "fix/deep/typo"} {
+		if err := Remove(branch, false); err != nil {
+			t.Fatalf("Remove(%s): %v", branch, err)
+		}
+	}
+
+	for _, gone := range []string{"feature", "fix/deep"} {
+		if _, err := os.Lstat(filepath.Join(worktreesDir, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s is still there (err = %v)", gone, err)
+		}
+	}
+	// fix still holds fix/other, and the worktrees dir itself is kept
+	if _, err := os.Stat(filepath.Join(worktreesDir, "fix", "other")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMovePrunesEmptyDirs(t *testing.T) {
+	dir := newTestRepo(t)
+	setBranchDirStyle(t, "nested")
+	worktreesDir := filepath.Join(filepath.Dir(dir), "repo.worktrees")
+
+	if _, err := AddFrom("feature/login", "HEAD", false); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := FindByBranch("feature/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setBranchDirStyle(t, "flat")
+	path, err := Move(*wt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(worktreesDir, "feature-login"); path != want {
+		t.Errorf("Move() = %q, want %q", path, want)
+	}
+	if _, err := os.Lstat(filepath.Join(worktreesDir, "feature")); !os.IsNotExist(err) {
+		t.Errorf("feature is still there (err = %v)", err)
+	}
+}
+
+func TestRemoveFlatBranch(t *testing.T) {
+	newTestRepo(t)
+	setBranchDirStyle(t, "flat")
+
+	path, err := AddFrom("feature/login", "HEAD", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("feature/login", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("%s is still there (err = %v)", path, err)
+	}
+}
+
+func TestRenderPathTemplate(t *testing.T) {
+	data := newPathData("feature/login", "widget", time.Date(2025, time.March, 14, 9, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		tmpl, want string
+		wantErr    bool
+	}{
+		{tmpl: "", want: "feature/login"},
+		{tmpl: "{{.Branch}}", want: "feature/login"},
+		{tmpl: "{{.Year}}/{{.Branch}}", want: "2025/feature/login"},
+		{tmpl: "{{.Date}}-{{.Repo}}", want: "2025-03-14-widget"},
+		{tmpl: "../{{.Branch}}", wantErr: true},
+		{tmpl: "{{.Branch}}/../../../x", wantErr: true},
+		{tmpl: "/tmp/{{.Branch}}", wantErr: true},
+		{tmpl: "{{.Missing}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := renderPathTemplate(tt.tmpl, data)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("renderPathTemplate(%q) = %q, want an error", tt.tmpl, got)
+			}
+			continue
+		}
+		if err != nil || got != filepath.FromSlash(tt.want) {
+			t.Errorf("renderPathTemplate(%q) = %q, %v; want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package worktree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucas-stellet/wk/internal/exit"
+)
+
+// AssignShortNames returns the shortest unique prefix of each worktree's
+// branch, keyed by branch, like git's abbreviated commit hashes. A branch
+// that is a prefix of another, such as "feat" next to "feature", gets its
+// full name. Detached worktrees have no short name.
+func AssignShortNames() (map[string]string, error) {
+	return NewRepo().AssignShortNames()
+}
+
+// AssignShortNames is like the package-level AssignShortNames, using r's
+// cache.
+func (r *Repo) AssignShortNames() (map[string]string, error) {
+	worktrees, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	return shortNames(worktreeBranchNames(worktrees)), nil
+}
+
+// shortNames returns the shortest prefix of each branch that no other branch
+// starts with.
+func shortNames(branches []string) map[string]string {
+	sorted := append([]string(nil), branches...)
+	sort.Strings(sorted)
+
+	names := make(map[string]string, len(sorted))
+	for i, branch := range sorted {
+		// In sorted order, the branches sharing the longest prefix with
+		// branch are its neighbours
+		n := 1
+		if i > 0 {
+			n = max(n, commonPrefixLen(branch, sorted[i-1])+1)
+		}
+		if i < len(sorted)-1 {
+			n = max(n, commonPrefixLen(branch, sorted[i+1])+1)
+		}
+		names[branch] = branch[:min(n, len(branch))]
+	}
+	return names
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// AmbiguousPrefixError is returned by FindByPrefix when several worktree
+// branches start with Prefix.
+type AmbiguousPrefixError struct {
+	Prefix     string
+	Candidates []string
+}
+
+func (e *AmbiguousPrefixError) Error() string {
+	return fmt.Sprintf("branch prefix '%s' is ambiguous: %s", e.Prefix, strings.Join(e.Candidates, ", "))
+}
+
+// FindByPrefix returns the worktree whose branch is prefix, or else the only
+// worktree whose branch starts with prefix. An ambiguous prefix returns an
+// *AmbiguousPrefixError listing the candidates.
+func FindByPrefix(prefix string) (*Worktree, error) {
+	worktrees, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []int
+	for i, wt := range worktrees {
+		if !hasBranch(wt) {
+			continue
+		}
+		if wt.Branch == prefix {
+			return &worktrees[i], nil
+		}
+		if strings.HasPrefix(wt.Branch, prefix) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, exit.WithCode(exit.NotFound, fmt.Errorf("no worktree branch starts with '%s'", prefix))
+	case 1:
+		return &worktrees[matches[0]], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, m := range matches {
+		candidates[i] = worktrees[m].Branch
+	}
+	sort.Strings(candidates)
+	return nil, exit.WithCode(exit.NotFound, &AmbiguousPrefixError{Prefix: prefix, Candidates: candidates})
+}
+
+// hasBranch reports whether wt has a branch checked out.
+func hasBranch(wt Worktree) bool {
+	return wt.Branch != "" && wt.Branch != "(detached)"
+}
+
+func worktreeBranchNames(worktrees []Worktree) []string {
+	var branches []string
+	for _, wt := range worktrees {
+		if hasBranch(wt) {
+			branches = append(branches, wt.Branch)
+		}
+	}
+	return branches
+}
@@ -0,0 +1,36 @@
+package worktree
+
+import "testing"
+
+func TestRepoAssignShortNames(t *testing.T) {
+	newTestRepo(t)
+	for _, branch := range []string{"feat", "feature", "fix"} {
+		git(t, "branch", branch)
+		if _, err := AddFrom(branch, "HEAD", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := countGit(t)
+
+	r := NewRepo()
+	if _, err := r.List(); err != nil {
+		t.Fatal(err)
+	}
+	names, err := r.AssignShortNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"main": "m", "feat": "feat", "feature": "featu", "fix": "fi"}
+	for branch, short := range want {
+		if names[branch] != short {
+			t.Errorf("short name of %s = %q, want %q", branch, names[branch], short)
+		}
+	}
+	if len(names) != len(want) {
+		t.Errorf("got %d short names, want %d: %v", len(names), len(want), names)
+	}
+	if n := count("worktree", "list"); n != 1 {
+		t.Errorf("git worktree list ran %d times, want 1", n)
+	}
+}
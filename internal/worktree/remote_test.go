@@ -0,0 +1,58 @@
+package worktree
+
+import "testing"
+
+func TestRemoteDetectsOnlyRemote(t *testing.T) {
+	newTestRepo(t)
+	git(t, "remote", "add", "upstream", "https://example.com/acme/widget.git")
+	git(t, "update-ref", "refs/remotes/upstream/feature", "HEAD")
+	count := countGit(t)
+
+	for range 3 {
+		if got := Remote(); got != "upstream" {
+			t.Fatalf("Remote() = %q, want %q", got, "upstream")
+		}
+	}
+	if n := count("remote"); n != 1 {
+		t.Errorf("detecting the remote ran 'git remote' %d times, want 1", n)
+	}
+
+	if !BranchExists("feature") {
+		t.Error("BranchExists(feature) = false, want true")
+	}
+
+	branches, err := ListBranches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, b := range branches {
+		if b.Name == "feature" {
+			found = true
+			if !b.IsRemote || b.Remote != "upstream" {
+				t.Errorf("feature: IsRemote = %v, Remote = %q, want true, %q", b.IsRemote, b.Remote, "upstream")
+			}
+		}
+	}
+	if !found {
+		t.Error("ListBranches() doesn't list feature")
+	}
+
+	name, err := NewRepo().Name()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "widget" {
+		t.Errorf("Name() = %q, want %q", name, "widget")
+	}
+}
+
+func TestRemotePrefersOrigin(t *testing.T) {
+	newTestRepo(t)
+	git(t, "remote", "add", "fork", "https://example.com/me/widget.git")
+	git(t, "remote", "add", "origin", "https://example.com/acme/widget.git")
+
+	if got := Remote(); got != DefaultRemote {
+		t.Errorf("Remote() = %q, want %q", got, DefaultRemote)
+	}
+}
@@ -0,0 +1,158 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Repo memoizes repository queries whose answers don't change while a
+// command runs, such as the worktree list and repository name, so that hot
+// paths spawn git once instead of once per call. Create one per command with
+// NewRepo; it is not safe for concurrent use. Call Invalidate after adding,
+// moving or removing worktrees.
+type Repo struct {
+	worktrees []Worktree
+	listErr   error
+	listed    bool
+
+	name    string
+	nameErr error
+	named   bool
+}
+
+// NewRepo returns a Repo with an empty cache.
+func NewRepo() *Repo {
+	return &Repo{}
+}
+
+// Invalidate drops cached results so the next query runs git again.
+func (r *Repo) Invalidate() {
+	*r = Repo{}
+}
+
+// List is like the package-level List, but only runs git the first time.
+func (r *Repo) List() ([]Worktree, error) {
+	if !r.listed {
+		r.worktrees, r.listErr = List()
+		r.listed = true
+	}
+	return r.worktrees, r.listErr
+}
+
+// MainWorktreePath returns the path of the main worktree. In a bare
+// repository, whose first entry has no checkout, it is the first worktree
+// that isn't bare, falling back to the bare repository itself.
+func (r *Repo) MainWorktreePath() (string, error) {
+	worktrees, err := r.List()
+	if err != nil {
+		return "", err
+	}
+
+	if len(worktrees) == 0 {
+		return "", fmt.Errorf("no worktrees found")
+	}
+
+	for _, wt := range worktrees {
+		if !wt.Bare {
+			return wt.Path, nil
+		}
+	}
+	return worktrees[0].Path, nil
+}
+
+// WorktreeBranches returns the branch names that have existing worktrees.
+func (r *Repo) WorktreeBranches() (map[string]bool, error) {
+	worktrees, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]bool)
+	for _, wt := range worktrees {
+		if wt.Branch != "" && wt.Branch != "(detached)" {
+			branches[wt.Branch] = true
+		}
+	}
+	return branches, nil
+}
+
+// Name returns the repository name from the remote URL or the main
+// worktree's directory name.
+func (r *Repo) Name() (string, error) {
+	if !r.named {
+		r.name, r.nameErr = r.repoName()
+		r.named = true
+	}
+	return r.name, r.nameErr
+}
+
+func (r *Repo) repoName() (string, error) {
+	// Try to get from the remote URL
+	url, err := gitOutput("config", "--get", "remote."+Remote()+".url")
+	if err == nil {
+		name := extractRepoName(url)
+		if name != "" {
+			return name, nil
+		}
+	}
+
+	// Fallback: use main worktree directory name
+	mainPath, err := r.MainWorktreePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(mainPath), nil
+}
+
+// WorktreesDir returns the path to the .worktrees directory.
+func (r *Repo) WorktreesDir() (string, error) {
+	repoName, err := r.Name()
+	if err != nil {
+		return "", err
+	}
+
+	mainPath, err := r.MainWorktreePath()
+	if err != nil {
+		return "", err
+	}
+
+	// ../reponame.worktrees
+	parentDir := filepath.Dir(mainPath)
+	return filepath.Join(parentDir, repoName+".worktrees"), nil
+}
+
+// IsInStandardLocation checks if a worktree path is inside the worktrees dir
+// and, for a branch with slashes, follows the branch dir style. Only that much
+// is checked, not the exact path from the path template, since templates may
+// depend on the creation date.
+func (r *Repo) IsInStandardLocation(wtPath string) (bool, error) {
+	worktreesDir, err := r.WorktreesDir()
+	if err != nil {
+		return false, err
+	}
+
+	// Main worktree and the bare repository don't need to be in standard location
+	mainPath, _ := r.MainWorktreePath()
+	if wtPath == mainPath {
+		return true, nil
+	}
+	worktrees, _ := r.List()
+	for _, wt := range worktrees {
+		if wt.Bare && wt.Path == wtPath {
+			return true, nil
+		}
+	}
+
+	if !strings.HasPrefix(wtPath, worktreesDir+string(filepath.Separator)) {
+		return false, nil
+	}
+	for _, wt := range worktrees {
+		if wt.Path == wtPath && strings.Contains(wt.Branch, "/") {
+			// A worktree laid out in the other style must be moved
+			rel := filepath.ToSlash(strings.TrimPrefix(wtPath, worktreesDir))
+			return strings.Contains(rel+"/", "/"+BranchDir(wt.Branch)+"/"), nil
+		}
+	}
+	return true, nil
+}
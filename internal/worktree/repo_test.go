@@ -0,0 +1,63 @@
+package worktree
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRepoListsWorktreesOnce(t *testing.T) {
+	dir := newTestRepo(t)
+	count := countGit(t)
+
+	r := NewRepo()
+	for _, branch := range []string{"one", "two", "three"} {
+		if _, err := r.StandardPath(branch); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := r.HasWorktree(branch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if main, err := r.MainWorktreePath(); err != nil || main != dir {
+		t.Fatalf("MainWorktreePath() = %q, %v; want %q", main, err, dir)
+	}
+	if _, err := r.FindByPath(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count("worktree", "list"); n != 1 {
+		t.Errorf("git worktree list ran %d times, want 1", n)
+	}
+
+	// The package-level functions use a fresh cache each time
+	if _, _, err := HasWorktree("one"); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("worktree", "list"); n != 2 {
+		t.Errorf("git worktree list ran %d times, want 2", n)
+	}
+}
+
+func TestRepoAddInvalidates(t *testing.T) {
+	dir := newTestRepo(t)
+
+	r := NewRepo()
+	if _, err := r.List(); err != nil {
+		t.Fatal(err)
+	}
+	path, err := r.AddFrom("feature", "HEAD", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(dir), "repo.worktrees", "feature"); path != want {
+		t.Errorf("AddFrom() = %q, want %q", path, want)
+	}
+
+	wt, err := r.FindByBranch("feature")
+	if err != nil {
+		t.Fatalf("FindByBranch after AddFrom: %v", err)
+	}
+	if wt.Path != path {
+		t.Errorf("FindByBranch().Path = %q, want %q", wt.Path, path)
+	}
+}
@@ -0,0 +1,44 @@
+package worktree
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStashExists(t *testing.T) {
+	newTestRepo(t)
+	if StashExists("stash@{0}") {
+		t.Error("StashExists(stash@{0}) = true with no stashes")
+	}
+
+	if err := os.WriteFile("file", []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, "add", "file")
+	git(t, "commit", "--quiet", "-m", "add file")
+	for _, content := range []string{"two\n", "three\n"} {
+		if err := os.WriteFile("file", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(t, "stash", "--quiet")
+	}
+
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"stash@{0}", true},
+		{"stash@{1}", true},
+		{"stash@{2}", false},
+		{"stash", true},
+		{"refs/stash", true},
+		{"HEAD", false},
+		{"main", false},
+		{"HEAD~1", false},
+	}
+	for _, tt := range tests {
+		if got := StashExists(tt.ref); got != tt.want {
+			t.Errorf("StashExists(%q) = %v, want %v", tt.ref, got, tt.want)
+		}
+	}
+}
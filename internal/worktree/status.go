@@ -0,0 +1,89 @@
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreatedAt returns when the linked worktree at path was created, based on
+// the .git file git writes when adding it. Returns the zero time for the main
+// worktree, whose .git is a directory.
+func CreatedAt(path string) (time.Time, error) {
+	info, err := os.Lstat(filepath.Join(path, ".git"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if info.IsDir() {
+		return time.Time{}, nil
+	}
+	return info.ModTime(), nil
+}
+
+// LastCommitDate returns the committer date of HEAD in the worktree at dir.
+func LastCommitDate(dir string) (time.Time, error) {
+	out, err := gitOutputIn(dir, "log", "-1", "--format=%ct")
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse commit date %q: %w", out, err)
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// IsDirty reports whether the worktree at dir has uncommitted changes,
+// including untracked files.
+func IsDirty(dir string) (bool, error) {
+	out, err := runGitIn(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return len(bytes.TrimSpace(out)) > 0, nil
+}
+
+// ShortStatus returns the output of 'git status --short' for the worktree at
+// dir, or an empty string when it has no uncommitted changes.
+func ShortStatus(dir string) (string, error) {
+	out, err := runGitIn(dir, "status", "--short")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// UnpushedCommits returns how many commits on branch are not on any
+// remote-tracking branch.
+func UnpushedCommits(branch string) (int, error) {
+	out, err := gitOutput("rev-list", "--count", branch, "--not", "--remotes")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// AheadBehind returns how many commits branch has that upstream doesn't
+// (ahead) and how many upstream has that branch doesn't (behind).
+func AheadBehind(branch, upstream string) (ahead, behind int, err error) {
+	out, err := gitOutput("rev-list", "--left-right", "--count", branch+"..."+upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	if ahead, err = strconv.Atoi(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	if behind, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, err
+	}
+	return ahead, behind, nil
+}
@@ -4,11 +4,17 @@ package worktree
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/lucas-stellet/wk/internal/exit"
 )
 
 // Worktree represents a git worktree entry.
@@ -16,37 +22,290 @@ type Worktree struct {
 	Path   string
 	Commit string
 	Branch string
+	// Locked is set for worktrees locked with 'git worktree lock', which
+	// git won't prune, move or remove.
+	Locked     bool
+	LockReason string
+	// Bare is set for the entry of a bare repository, which has no checkout.
+	Bare bool
+}
+
+// DefaultRemote is the remote name used when none is configured or detected.
+const DefaultRemote = "origin"
+
+// configuredRemote is the remote name set via SetRemote.
+var configuredRemote string
+
+// detectedRemote caches the remote Remote picks when none is configured, so
+// it runs 'git remote' at most once per process.
+var detectedRemote string
+
+// SetRemote sets the remote name used for remote branch lookups.
+// An empty name restores auto-detection.
+func SetRemote(name string) {
+	configuredRemote = name
+	detectedRemote = ""
+}
+
+// Remote returns the remote name to use. If none was set via SetRemote, it
+// uses origin when present, or the only remote when there is exactly one.
+func Remote() string {
+	if configuredRemote != "" {
+		return configuredRemote
+	}
+	if detectedRemote == "" {
+		detectedRemote = detectRemote()
+	}
+	return detectedRemote
+}
+
+// detectRemote picks origin when present, or the only remote when there is
+// exactly one.
+func detectRemote() string {
+	remotes := Remotes()
+	for _, r := range remotes {
+		if r == DefaultRemote {
+			return DefaultRemote
+		}
+	}
+	if len(remotes) == 1 {
+		return remotes[0]
+	}
+	return DefaultRemote
+}
+
+// Remotes returns the names of the repository's remotes.
+func Remotes() []string {
+	out, err := runGit("remote")
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
+// TrackingRemote returns the remote that has branch, preferring Remote() when
+// several do. Returns "" if no remote has it.
+func TrackingRemote(branch string) string {
+	primary := Remote()
+	if remoteBranchExists(primary, branch) {
+		return primary
+	}
+	for _, r := range Remotes() {
+		if r != primary && remoteBranchExists(r, branch) {
+			return r
+		}
+	}
+	return ""
+}
+
+// remoteBranchExists reports whether remote has a tracking branch named branch.
+func remoteBranchExists(remote, branch string) bool {
+	_, err := runGit("show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch)
+	return err == nil
+}
+
+// AddAction is what Add does for a branch.
+type AddAction int
+
+const (
+	// AddExisting checks out an existing local branch.
+	AddExisting AddAction = iota
+	// AddTracking creates a local branch tracking the remote branch.
+	AddTracking
+	// AddNew creates a new branch from the base.
+	AddNew
+)
+
+// PlanAdd reports what Add will do for branch.
+func PlanAdd(branch string) AddAction {
+	if _, err := runGit("show-ref", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		return AddExisting
+	}
+	if BranchExists(branch) {
+		return AddTracking
+	}
+	return AddNew
 }
 
 // Add creates a new worktree for the given branch.
 // If the branch doesn't exist, it creates a new branch from HEAD.
 // Returns the path where the worktree was created.
 // Worktrees are created in the standard location: ../<reponame>.worktrees/<branch>
-func Add(branch string) (string, error) {
-	worktreesDir, err := GetWorktreesDir()
+// With force, git adds the worktree even if the path is registered to a
+// missing worktree or the branch is checked out elsewhere.
+// If a check fails after git created the worktree, its path is returned
+// along with the error.
+func Add(branch string, force bool) (string, error) {
+	return AddFrom(branch, "HEAD", force)
+}
+
+// AddFrom is like Add but creates a missing branch from base instead of HEAD.
+func AddFrom(branch, base string, force bool) (string, error) {
+	return NewRepo().AddFrom(branch, base, force)
+}
+
+// AddFrom is like the package-level AddFrom, using r's cache. r is
+// invalidated once git has added the worktree.
+func (r *Repo) AddFrom(branch, base string, force bool) (string, error) {
+	worktreePath, err := r.StandardPath(branch)
 	if err != nil {
 		return "", err
 	}
+	if err := r.CheckPath(worktreePath, branch); err != nil {
+		return "", err
+	}
 
 	// Create worktrees directory if it doesn't exist
-	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
 		return "", fmt.Errorf("failed to create worktrees directory: %w", err)
 	}
 
-	worktreePath := filepath.Join(worktreesDir, branch)
+	args := []string{"worktree", "add"}
+	if force {
+		args = append(args, "--force")
+	}
+	switch PlanAdd(branch) {
+	case AddExisting:
+		args = append(args, worktreePath, branch)
+	case AddTracking:
+		// Name the remote explicitly; git's guess fails when several
+		// remotes have the branch
+		remoteRef := TrackingRemote(branch) + "/" + branch
+		args = append(args, "--track", "-b", branch, worktreePath, remoteRef)
+	default:
+		// Branch doesn't exist, create it from base
+		args = append(args, "-b", branch, worktreePath, base)
+	}
 
-	var cmd *exec.Cmd
-	if BranchExists(branch) {
-		// Branch exists, just create worktree
-		cmd = exec.Command("git", "worktree", "add", worktreePath, branch)
+	if _, err := runGit(args...); err != nil {
+		return "", err
+	}
+	r.Invalidate()
+
+	if err := VerifyLink(worktreePath); err != nil {
+		return worktreePath, err
+	}
+
+	return worktreePath, nil
+}
+
+// DetachedPath returns the path AddDetached uses for ref, named after the
+// short commit hash.
+func DetachedPath(ref string) (string, error) {
+	return NewRepo().DetachedPath(ref)
+}
+
+// DetachedPath is like the package-level DetachedPath, using r's cache.
+func (r *Repo) DetachedPath(ref string) (string, error) {
+	short, err := gitOutput("rev-parse", "--short", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not a valid revision", ref)
+	}
+	return r.StandardPath(short)
+}
+
+// ResolveUpstream resolves a remote branch given as "remote/branch" (e.g.
+// "origin/feature-x") or as a bare branch name, which is looked up on the
+// remotes as by TrackingRemote. Returns the remote and the branch on it.
+func ResolveUpstream(ref string) (remote, branch string, err error) {
+	remote, branch = splitRemoteRef(ref, Remotes())
+	if remote == "" {
+		remote, branch = TrackingRemote(ref), ref
+	}
+	if remote == "" || !remoteBranchExists(remote, branch) {
+		return "", "", exit.WithCode(exit.NotFound, fmt.Errorf("remote branch '%s' not found; run 'git fetch' first", ref))
+	}
+	return remote, branch, nil
+}
+
+// AddTrack creates a worktree for branch with remote/remoteBranch as its
+// upstream. A missing branch is created from the remote branch; an existing
+// one is checked out and its upstream set. The upstream is verified after
+// creation. force and the path returned with errors are as for Add.
+func AddTrack(branch, remote, remoteBranch string, force bool) (string, error) {
+	return NewRepo().AddTrack(branch, remote, remoteBranch, force)
+}
+
+// AddTrack is like the package-level AddTrack, using r's cache. r is
+// invalidated once git has added the worktree.
+func (r *Repo) AddTrack(branch, remote, remoteBranch string, force bool) (string, error) {
+	worktreePath, err := r.StandardPath(branch)
+	if err != nil {
+		return "", err
+	}
+	if err := r.CheckPath(worktreePath, branch); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create worktrees directory: %w", err)
+	}
+
+	upstream := remote + "/" + remoteBranch
+	args := []string{"worktree", "add"}
+	if force {
+		args = append(args, "--force")
+	}
+	if PlanAdd(branch) == AddExisting {
+		args = append(args, worktreePath, branch)
 	} else {
-		// Branch doesn't exist, create it from HEAD
-		cmd = exec.Command("git", "worktree", "add", "-b", branch, worktreePath, "HEAD")
+		args = append(args, "--track", "-b", branch, worktreePath, upstream)
 	}
+	if _, err := runGit(args...); err != nil {
+		return "", err
+	}
+	r.Invalidate()
 
-	output, err := cmd.CombinedOutput()
+	if err := VerifyLink(worktreePath); err != nil {
+		return worktreePath, err
+	}
+
+	// Set the upstream explicitly: an existing branch keeps its old one, and
+	// branch.autoSetupMerge can keep git from setting it for a new branch
+	if _, err := runGit("branch", "--set-upstream-to="+upstream, branch); err != nil {
+		return worktreePath, err
+	}
+	if got := GetUpstream(branch); got != upstream {
+		return worktreePath, fmt.Errorf("worktree created at %s, but its upstream is '%s' instead of '%s'", worktreePath, got, upstream)
+	}
+
+	return worktreePath, nil
+}
+
+// AddDetached creates a worktree with a detached HEAD at ref, without
+// creating a branch. The directory is named after the short commit hash.
+// Returns the path to the created worktree. force and the path returned with
+// errors are as for Add.
+func AddDetached(ref string, force bool) (string, error) {
+	return NewRepo().AddDetached(ref, force)
+}
+
+// AddDetached is like the package-level AddDetached, using r's cache. r is
+// invalidated once git has added the worktree.
+func (r *Repo) AddDetached(ref string, force bool) (string, error) {
+	worktreePath, err := r.DetachedPath(ref)
 	if err != nil {
-		return "", fmt.Errorf("git worktree add failed: %s", strings.TrimSpace(string(output)))
+		return "", err
+	}
+	if err := r.CheckPath(worktreePath, "(detached)"); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create worktrees directory: %w", err)
+	}
+
+	args := []string{"worktree", "add", "--detach"}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := runGit(append(args, worktreePath, ref)...); err != nil {
+		return "", err
+	}
+	r.Invalidate()
+
+	if err := VerifyLink(worktreePath); err != nil {
+		return worktreePath, err
 	}
 
 	return worktreePath, nil
@@ -54,13 +313,12 @@ func Add(branch string) (string, error) {
 
 // List returns all worktrees in the repository.
 func List() ([]Worktree, error) {
-	cmd := exec.Command("git", "worktree", "list", "--porcelain")
-	output, err := cmd.Output()
+	out, err := runGit("worktree", "list", "--porcelain")
 	if err != nil {
-		return nil, fmt.Errorf("git worktree list failed: %w", err)
+		return nil, err
 	}
 
-	return parseWorktreeList(output)
+	return parseWorktreeList(out)
 }
 
 func parseWorktreeList(data []byte) ([]Worktree, error) {
@@ -85,6 +343,13 @@ func parseWorktreeList(data []byte) ([]Worktree, error) {
 			current.Branch = strings.TrimPrefix(branch, "refs/heads/")
 		case line == "detached":
 			current.Branch = "(detached)"
+		case line == "bare":
+			current.Bare = true
+		case line == "locked":
+			current.Locked = true
+		case strings.HasPrefix(line, "locked "):
+			current.Locked = true
+			current.LockReason = strings.TrimPrefix(line, "locked ")
 		}
 	}
 
@@ -97,112 +362,382 @@ func parseWorktreeList(data []byte) ([]Worktree, error) {
 
 // Remove removes a worktree by path or branch name.
 // If force is true, removes even if worktree has uncommitted changes.
+// Directories left empty in the worktrees dir, like feature/ after removing
+// feature/login, are removed too.
 func Remove(target string, force bool) error {
+	dir := target
+	if wt, err := FindByBranch(target); err == nil {
+		dir = wt.Path
+	}
+
 	args := []string{"worktree", "remove"}
 	if force {
 		args = append(args, "--force")
 	}
-	args = append(args, target)
+	// git only takes a path, or the worktree's name, which isn't the
+	// branch for branches with slashes
+	args = append(args, dir)
 
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git worktree remove failed: %s", strings.TrimSpace(string(output)))
+	if _, err := runGit(args...); err != nil {
+		return err
+	}
+	if dir, err := filepath.Abs(dir); err == nil {
+		pruneEmptyDirs(filepath.Dir(dir))
 	}
 	return nil
 }
 
-// GetMainWorktreePath returns the path of the main worktree (bare repo or main checkout).
-func GetMainWorktreePath() (string, error) {
-	worktrees, err := List()
-	if err != nil {
-		return "", err
+// Lock locks the worktree at path so git won't prune, move or remove it,
+// e.g. while it is on removable media. reason may be empty.
+func Lock(path, reason string) error {
+	args := []string{"worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
 	}
+	args = append(args, path)
 
-	if len(worktrees) == 0 {
-		return "", fmt.Errorf("no worktrees found")
-	}
+	_, err := runGit(args...)
+	return err
+}
 
-	return worktrees[0].Path, nil
+// Unlock unlocks the worktree at path.
+func Unlock(path string) error {
+	_, err := runGit("worktree", "unlock", path)
+	return err
+}
+
+// GetMainWorktreePath returns the path of the main worktree (bare repo or main checkout).
+func GetMainWorktreePath() (string, error) {
+	return NewRepo().MainWorktreePath()
 }
 
 // HasUncommittedChanges checks if there are uncommitted changes in the working directory.
 func HasUncommittedChanges() (bool, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
-	output, err := cmd.Output()
+	out, err := runGit("status", "--porcelain")
 	if err != nil {
-		return false, fmt.Errorf("git status failed: %w", err)
+		return false, err
 	}
-	return len(bytes.TrimSpace(output)) > 0, nil
+	return len(bytes.TrimSpace(out)) > 0, nil
 }
 
 // GetCurrentBranch returns the name of the current branch.
 func GetCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("git rev-parse failed: %w", err)
-	}
-	return strings.TrimSpace(string(output)), nil
+	return gitOutput("rev-parse", "--abbrev-ref", "HEAD")
 }
 
-// BranchExists checks if a branch exists locally or as a remote tracking branch.
+// BranchExists checks if a branch exists locally or as a remote tracking
+// branch of any remote.
 func BranchExists(branch string) bool {
 	// Check local branch
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
-	if cmd.Run() == nil {
+	if _, err := runGit("show-ref", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
 		return true
 	}
 
-	// Check remote tracking branch
-	cmd = exec.Command("git", "show-ref", "--verify", "--quiet", "refs/remotes/origin/"+branch)
-	return cmd.Run() == nil
+	// Check remote tracking branches
+	return TrackingRemote(branch) != ""
+}
+
+// TagExists reports whether tag is a tag in the repository.
+func TagExists(tag string) bool {
+	_, err := runGit("show-ref", "--verify", "--quiet", "refs/tags/"+tag)
+	return err == nil
+}
+
+// GetBranchAt returns the name of the branch checked out in the worktree at dir.
+func GetBranchAt(dir string) (string, error) {
+	return gitOutputIn(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// GetGitDir returns the absolute git admin directory of the worktree at dir
+// (e.g. <repo>/.git/worktrees/<name> for linked worktrees).
+func GetGitDir(dir string) (string, error) {
+	return gitOutputIn(dir, "rev-parse", "--absolute-git-dir")
+}
+
+// GetUpstream returns the upstream tracking branch for branch (e.g. "origin/main").
+// Returns an empty string if the branch has no upstream configured.
+func GetUpstream(branch string) string {
+	upstream, err := gitOutput("rev-parse", "--abbrev-ref", branch+"@{upstream}")
+	if err != nil {
+		return ""
+	}
+	return upstream
+}
+
+// IsTrackedIdentical reports whether file is tracked in the srcDir worktree and
+// its committed version matches the committed version in the dstDir worktree.
+func IsTrackedIdentical(srcDir, dstDir, file string) bool {
+	srcHash, err := committedObject(srcDir, file)
+	if err != nil {
+		return false
+	}
+	dstHash, err := committedObject(dstDir, file)
+	if err != nil {
+		return false
+	}
+	return srcHash == dstHash
+}
+
+// committedObject returns the object hash of path at HEAD in the worktree at dir.
+func committedObject(dir, path string) (string, error) {
+	return gitOutputIn(dir, "rev-parse", "--verify", "--quiet", "HEAD:"+filepath.ToSlash(path))
+}
+
+// SnapshotCommit returns a commit representing the working tree of the
+// worktree at dir, including uncommitted changes to tracked files.
+// Nothing in the worktree is modified. Returns HEAD when the tree is clean.
+func SnapshotCommit(dir string) (string, error) {
+	commit, err := gitOutputIn(dir, "stash", "create")
+	if err != nil {
+		return "", err
+	}
+
+	if commit != "" {
+		return commit, nil
+	}
+
+	return gitOutputIn(dir, "rev-parse", "HEAD")
+}
+
+// DeleteBranch deletes a local branch. If force is true, deletes it even if
+// it is not fully merged (git branch -D).
+func DeleteBranch(branch string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+
+	_, err := runGit("branch", flag, branch)
+	return err
+}
+
+// IsMerged reports whether branch is fully merged into the default branch,
+// or into its remote-tracking branch when there is no local one.
+func IsMerged(branch string) (bool, error) {
+	base, err := DefaultBase()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = runGit("merge-base", "--is-ancestor", branch, base)
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+// DefaultBranch returns the name of the repository's default branch, based
+// on the remote's HEAD when available and falling back to a main or master
+// branch. Returns an error if none of these exist.
+func DefaultBranch() (string, error) {
+	remote := Remote()
+	if ref, err := gitOutput("symbolic-ref", "--quiet", "--short", "refs/remotes/"+remote+"/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, remote+"/"), nil
+	}
+
+	for _, name := range []string{"main", "master"} {
+		if BranchExists(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine the default branch: %s/HEAD is not set and there is no main or master branch", remote)
+}
+
+// DefaultBase returns a revision for the default branch that can be used as
+// the base of a new branch: the local branch if it exists, otherwise the
+// remote-tracking one.
+func DefaultBase() (string, error) {
+	name, err := DefaultBranch()
+	if err != nil {
+		return "", err
+	}
+	if PlanAdd(name) == AddExisting {
+		return name, nil
+	}
+	return Remote() + "/" + name, nil
 }
 
 // CreateStash creates a stash with the given message.
 func CreateStash(message string) error {
-	cmd := exec.Command("git", "stash", "push", "-m", message)
-	output, err := cmd.CombinedOutput()
+	_, err := runGit("stash", "push", "-m", message)
+	return err
+}
+
+// StashPrefix starts the message of stashes created by wk, which are named
+// "wk:<branch>:<timestamp>". Branch names can't contain colons, so the branch
+// can be matched reliably.
+const StashPrefix = "wk:"
+
+// StashMessage returns the message for a stash wk creates for branch.
+func StashMessage(branch, timestamp string) string {
+	return StashPrefix + branch + ":" + timestamp
+}
+
+// FindStashForBranch returns the ref (e.g. "stash@{2}") of the most recent
+// stash wk created for branch. found is false if there is none.
+func FindStashForBranch(branch string) (ref string, found bool, err error) {
+	out, err := gitOutput("stash", "list", "--format=%gd%x00%gs")
 	if err != nil {
-		return fmt.Errorf("git stash failed: %s", strings.TrimSpace(string(output)))
+		return "", false, err
 	}
-	return nil
+
+	prefix := StashMessage(branch, "")
+	for _, line := range strings.Split(out, "\n") {
+		ref, subject, ok := strings.Cut(line, "\x00")
+		if !ok {
+			continue
+		}
+		// Subjects of stashes pushed with a message are "On <branch>: <message>"
+		_, message, _ := strings.Cut(subject, ": ")
+		if strings.HasPrefix(message, prefix) {
+			return ref, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// StashExists reports whether ref names a stash entry: one listed by 'git
+// stash list', like stash@{2}, or "stash" or "refs/stash" for the latest.
+// Other commits, like a branch or HEAD, aren't stashes.
+func StashExists(ref string) bool {
+	out, err := runGit("stash", "list", "--format=%gd")
+	if err != nil {
+		return false
+	}
+	entries := strings.Fields(string(out))
+	if ref == "stash" || ref == "refs/stash" {
+		return len(entries) > 0
+	}
+	return slices.Contains(entries, ref)
+}
+
+// ApplyStash applies the stash ref to the worktree at dir, keeping the stash.
+// Returns an error listing the output if the stash did not apply cleanly.
+func ApplyStash(dir, ref string) error {
+	_, err := runGitIn(dir, "stash", "apply", ref)
+	var gitErr *gitError
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.output, "CONFLICT") {
+		return fmt.Errorf("stash %s applied with conflicts in %s:\n%s", ref, dir, gitErr.output)
+	}
+	return err
 }
 
-// FindByBranch finds a worktree by its branch name.
+// PopStash applies the stash ref to the worktree at dir and drops it. If the
+// stash did not apply cleanly it is kept, and the error lists the output.
+func PopStash(dir, ref string) error {
+	_, err := runGitIn(dir, "stash", "pop", ref)
+	var gitErr *gitError
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.output, "CONFLICT") {
+		return fmt.Errorf("stash %s applied with conflicts in %s and was kept:\n%s", ref, dir, gitErr.output)
+	}
+	return err
+}
+
+// FindByBranch finds a worktree by its branch name. The result points into
+// a fresh list, so callers may modify it.
 func FindByBranch(branch string) (*Worktree, error) {
-	worktrees, err := List()
+	return NewRepo().FindByBranch(branch)
+}
+
+// FindByBranch is like the package-level FindByBranch, using r's cache. The
+// worktree returned points into the cached list.
+func (r *Repo) FindByBranch(branch string) (*Worktree, error) {
+	worktrees, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range worktrees {
+		if worktrees[i].Branch == branch {
+			return &worktrees[i], nil
+		}
+	}
+	return nil, exit.WithCode(exit.NotFound, fmt.Errorf("worktree for branch '%s' not found", branch))
+}
+
+// FindByPath returns the registered worktree at path, which need not exist
+// on disk.
+func FindByPath(path string) (*Worktree, error) {
+	return NewRepo().FindByPath(path)
+}
+
+// FindByPath is like the package-level FindByPath, using r's cache.
+func (r *Repo) FindByPath(path string) (*Worktree, error) {
+	worktrees, err := r.List()
 	if err != nil {
 		return nil, err
 	}
 
+	path = filepath.Clean(path)
+	for i := range worktrees {
+		if filepath.Clean(worktrees[i].Path) == path {
+			return &worktrees[i], nil
+		}
+	}
+	return nil, exit.WithCode(exit.NotFound, fmt.Errorf("no worktree at %s", path))
+}
+
+// HasWorktree reports whether branch is checked out in a worktree, and
+// returns the worktree's path if so.
+func HasWorktree(branch string) (bool, string, error) {
+	return NewRepo().HasWorktree(branch)
+}
+
+// HasWorktree is like the package-level HasWorktree, using r's cache.
+func (r *Repo) HasWorktree(branch string) (bool, string, error) {
+	worktrees, err := r.List()
+	if err != nil {
+		return false, "", err
+	}
+
 	for _, wt := range worktrees {
 		if wt.Branch == branch {
-			return &wt, nil
+			return true, wt.Path, nil
 		}
 	}
-	return nil, fmt.Errorf("worktree for branch '%s' not found", branch)
+	return false, "", nil
 }
 
-// GetRepoName returns the repository name from the remote origin URL or directory name.
-func GetRepoName() (string, error) {
-	// Try to get from remote origin
-	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
-	output, err := cmd.Output()
-	if err == nil {
-		url := strings.TrimSpace(string(output))
-		name := extractRepoName(url)
-		if name != "" {
-			return name, nil
+// FindByCommit returns the first worktree whose HEAD is at commit.
+func FindByCommit(commit string) (*Worktree, error) {
+	worktrees, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range worktrees {
+		if worktrees[i].Commit == commit {
+			return &worktrees[i], nil
 		}
 	}
+	return nil, exit.WithCode(exit.NotFound, fmt.Errorf("no worktree has commit %s checked out", commit))
+}
 
-	// Fallback: use main worktree directory name
-	mainPath, err := GetMainWorktreePath()
+// ResolveRevision resolves a git revision such as "@{-1}" or "HEAD~2" with
+// git rev-parse. Returns the local branch the revision names, if any, and the
+// full commit hash it points to.
+func ResolveRevision(rev string) (branch, commit string, err error) {
+	commit, err = gitOutput("rev-parse", "--verify", "--quiet", rev+"^{commit}")
 	if err != nil {
-		return "", err
+		return "", "", fmt.Errorf("'%s' is not a branch or revision", rev)
 	}
-	return filepath.Base(mainPath), nil
+
+	if ref, err := gitOutput("rev-parse", "--symbolic-full-name", rev); err == nil {
+		if strings.HasPrefix(ref, "refs/heads/") {
+			branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return branch, commit, nil
+}
+
+// GetRepoName returns the repository name from the remote URL or directory name.
+func GetRepoName() (string, error) {
+	return NewRepo().Name()
 }
 
 // extractRepoName extracts the repository name from a git URL.
@@ -230,86 +765,165 @@ func extractRepoName(url string) string {
 
 // GetWorktreesDir returns the path to the .worktrees directory.
 func GetWorktreesDir() (string, error) {
-	repoName, err := GetRepoName()
+	return NewRepo().WorktreesDir()
+}
+
+// IsInStandardLocation checks if a worktree path is inside the worktrees dir.
+// See Repo.IsInStandardLocation.
+func IsInStandardLocation(wtPath string) (bool, error) {
+	return NewRepo().IsInStandardLocation(wtPath)
+}
+
+// Move moves a worktree to the standard location.
+func Move(wt Worktree) (string, error) {
+	newPath, err := StandardPath(wt.Branch)
 	if err != nil {
 		return "", err
 	}
+	return MoveTo(wt, newPath)
+}
 
-	mainPath, err := GetMainWorktreePath()
+// MoveTo moves a worktree to newPath, creating its parent directories.
+// newPath must not exist or be inside an existing worktree. Returns the
+// absolute path the worktree was moved to.
+func MoveTo(wt Worktree, newPath string) (string, error) {
+	newPath, err := filepath.Abs(newPath)
 	if err != nil {
 		return "", err
 	}
 
-	// ../reponame.worktrees
-	parentDir := filepath.Dir(mainPath)
-	return filepath.Join(parentDir, repoName+".worktrees"), nil
-}
+	if _, err := os.Lstat(newPath); err == nil {
+		return "", fmt.Errorf("destination %s already exists", newPath)
+	}
 
-// IsInStandardLocation checks if a worktree path follows the standard pattern.
-func IsInStandardLocation(wtPath string) (bool, error) {
-	worktreesDir, err := GetWorktreesDir()
-	if err != nil {
-		return false, err
+	if err := NewRepo().CheckPath(newPath, wt.Branch); err != nil {
+		return "", err
 	}
 
-	// Main worktree doesn't need to be in standard location
-	mainPath, _ := GetMainWorktreePath()
-	if wtPath == mainPath {
-		return true, nil
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create parent directory: %w", err)
 	}
 
-	return strings.HasPrefix(wtPath, worktreesDir), nil
+	if _, err := runGit("worktree", "move", wt.Path, newPath); err != nil {
+		if isCrossDeviceError(err.Error()) {
+			// git can't rename across filesystems; copy and re-register instead
+			if err := moveAcrossDevices(wt.Path, newPath); err != nil {
+				return "", err
+			}
+			pruneEmptyDirs(filepath.Dir(wt.Path))
+			return newPath, nil
+		}
+		return "", err
+	}
+
+	pruneEmptyDirs(filepath.Dir(wt.Path))
+	return newPath, nil
 }
 
-// Move moves a worktree to the standard location.
-func Move(wt Worktree) (string, error) {
-	worktreesDir, err := GetWorktreesDir()
+// Rename renames oldBranch to newBranch and moves its worktree to the
+// standard location for the new name. Returns the new worktree path.
+// The main worktree keeps its path; only the branch is renamed.
+func Rename(oldBranch, newBranch string) (string, error) {
+	wt, err := FindByBranch(oldBranch)
 	if err != nil {
 		return "", err
 	}
 
-	// Create worktrees directory if it doesn't exist
-	if err := os.MkdirAll(worktreesDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create worktrees directory: %w", err)
+	mainPath, err := GetMainWorktreePath()
+	if err != nil {
+		return "", err
 	}
 
-	newPath := filepath.Join(worktreesDir, wt.Branch)
+	isMain := wt.Path == mainPath
+	if !isMain {
+		inside, err := IsCurrentDirInside(wt.Path)
+		if err != nil {
+			return "", err
+		}
+		if inside {
+			return "", fmt.Errorf("cannot move worktree '%s' while it is the current directory\n\nRun this command from another worktree (e.g. %s)", oldBranch, mainPath)
+		}
+	}
+
+	if _, err := runGit("branch", "-m", oldBranch, newBranch); err != nil {
+		return "", err
+	}
+
+	if isMain {
+		return wt.Path, nil
+	}
 
-	cmd := exec.Command("git", "worktree", "move", wt.Path, newPath)
-	output, err := cmd.CombinedOutput()
+	newPath, err := Move(Worktree{Path: wt.Path, Commit: wt.Commit, Branch: newBranch})
 	if err != nil {
-		return "", fmt.Errorf("git worktree move failed: %s", strings.TrimSpace(string(output)))
+		// Restore the original branch name so branch and directory stay in sync
+		runGit("branch", "-m", newBranch, oldBranch)
+		return "", err
 	}
 
 	return newPath, nil
 }
 
+// IsCurrentDirInside reports whether the working directory is dir or inside it.
+func IsCurrentDirInside(dir string) (bool, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, fmt.Errorf("get working directory: %w", err)
+	}
+
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		return false, err
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, err
+	}
+
+	return pathInside(dir, wd), nil
+}
+
+// pathInside reports whether path is dir or inside it. Both must be
+// absolute and clean.
+func pathInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // Branch represents a git branch with metadata.
 type Branch struct {
-	Name        string
-	IsRemote    bool
-	IsLocal     bool
+	Name     string
+	IsRemote bool
+	IsLocal  bool
+	// Remote is the remote the branch was found on, preferring Remote().
+	// Empty for local-only branches.
+	Remote      string
 	CommitShort string
-	CommitDate  string
+	CommitDate  time.Time
 }
 
 // ListBranches returns all branches (local and remote) with metadata.
+// Local branches come first, then remote-only branches of Remote(), then
+// those only found on other remotes, each sorted by name. A branch on
+// several remotes is listed once.
 func ListBranches() ([]Branch, error) {
-	// Get local branches with commit info
-	// Format: %(refname:short)|%(objectname:short)|%(committerdate:relative)
-	cmd := exec.Command("git", "for-each-ref",
-		"--format=%(refname:short)|%(objectname:short)|%(committerdate:relative)",
-		"refs/heads/", "refs/remotes/origin/")
-	output, err := cmd.Output()
+	primary := Remote()
+	remotes := Remotes()
+
+	// Format: %(refname)|%(objectname:short)|%(committerdate:unix)
+	out, err := runGit("for-each-ref",
+		"--sort=refname",
+		"--format=%(refname)|%(objectname:short)|%(committerdate:unix)",
+		"refs/heads/", "refs/remotes/")
 	if err != nil {
-		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+		return nil, err
 	}
 
-	localBranches := make(map[string]bool)
-	remoteBranches := make(map[string]Branch)
-	var branches []Branch
+	var branches, primaryBranches, otherBranches []Branch
 
-	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner := bufio.NewScanner(bytes.NewReader(out))
 	for scanner.Scan() {
 		line := scanner.Text()
 		parts := strings.SplitN(line, "|", 3)
@@ -317,26 +931,15 @@ func ListBranches() ([]Branch, error) {
 			continue
 		}
 
-		name := parts[0]
+		ref := parts[0]
 		commitShort := parts[1]
-		commitDate := parts[2]
+		var commitDate time.Time
+		if unix, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+			commitDate = time.Unix(unix, 0)
+		}
 
-		if strings.HasPrefix(name, "origin/") {
-			// Remote branch
-			remoteName := strings.TrimPrefix(name, "origin/")
-			if remoteName == "HEAD" {
-				continue
-			}
-			remoteBranches[remoteName] = Branch{
-				Name:        remoteName,
-				IsRemote:    true,
-				IsLocal:     false,
-				CommitShort: commitShort,
-				CommitDate:  commitDate,
-			}
-		} else {
+		if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
 			// Local branch
-			localBranches[name] = true
 			branches = append(branches, Branch{
 				Name:        name,
 				IsRemote:    false,
@@ -344,37 +947,92 @@ func ListBranches() ([]Branch, error) {
 				CommitShort: commitShort,
 				CommitDate:  commitDate,
 			})
+			continue
+		}
+
+		// Remote branch
+		remote, name := splitRemoteRef(strings.TrimPrefix(ref, "refs/remotes/"), remotes)
+		if remote == "" || name == "HEAD" {
+			continue
+		}
+		b := Branch{
+			Name:        name,
+			IsRemote:    true,
+			IsLocal:     false,
+			Remote:      remote,
+			CommitShort: commitShort,
+			CommitDate:  commitDate,
+		}
+		if remote == primary {
+			primaryBranches = append(primaryBranches, b)
+		} else {
+			otherBranches = append(otherBranches, b)
 		}
 	}
 
-	// Mark local branches that also exist on remote
+	index := make(map[string]int, len(branches))
 	for i, b := range branches {
-		if _, exists := remoteBranches[b.Name]; exists {
+		index[b.Name] = i
+	}
+
+	// Mark local branches that also exist on the primary remote and add the
+	// remaining remote-only branches
+	for _, b := range primaryBranches {
+		if i, exists := index[b.Name]; exists {
 			branches[i].IsRemote = true
-			delete(remoteBranches, b.Name)
+			branches[i].Remote = primary
+			continue
 		}
+		index[b.Name] = len(branches)
+		branches = append(branches, b)
 	}
-
-	// Add remaining remote-only branches
-	for _, b := range remoteBranches {
+	for _, b := range otherBranches {
+		if _, exists := index[b.Name]; exists {
+			continue
+		}
+		index[b.Name] = len(branches)
 		branches = append(branches, b)
 	}
 
 	return branches, scanner.Err()
 }
 
-// ListWorktreeBranches returns the branch names that have existing worktrees.
-func ListWorktreeBranches() (map[string]bool, error) {
-	worktrees, err := List()
+// ListRefs returns the short names of local branches, remote-tracking
+// branches and tags, in that order, for completing refs. Symbolic remote
+// HEADs like origin/HEAD are left out.
+func ListRefs() ([]string, error) {
+	out, err := runGit("for-each-ref", "--format=%(refname)%00%(refname:short)", "refs/heads", "refs/remotes", "refs/tags")
 	if err != nil {
 		return nil, err
 	}
 
-	branches := make(map[string]bool)
-	for _, wt := range worktrees {
-		if wt.Branch != "" && wt.Branch != "(detached)" {
-			branches[wt.Branch] = true
+	var refs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		full, short, ok := strings.Cut(line, "\x00")
+		if !ok || strings.HasSuffix(full, "/HEAD") {
+			continue
+		}
+		refs = append(refs, short)
+	}
+	return refs, nil
+}
+
+// splitRemoteRef splits a remote-tracking ref like "upstream/feature/x" into
+// the remote and branch name, matching the longest remote name since remote
+// names may contain slashes. Returns an empty remote if none matches.
+func splitRemoteRef(ref string, remotes []string) (remote, branch string) {
+	for _, r := range remotes {
+		if strings.HasPrefix(ref, r+"/") && len(r) > len(remote) {
+			remote = r
 		}
 	}
-	return branches, nil
+	if remote == "" {
+		return "", ""
+	}
+	return remote, strings.TrimPrefix(ref, remote+"/")
+}
+
+// ListWorktreeBranches returns the branch names that have existing worktrees.
+func ListWorktreeBranches() (map[string]bool, error) {
+	return NewRepo().WorktreeBranches()
 }
@@ -0,0 +1,110 @@
+package worktree
+
+import "testing"
+
+// bareList is 'git worktree list --porcelain' output for a bare clone with
+// two worktrees.
+const bareList = `worktree /srv/repo.git
+bare
+
+worktree /srv/repo.git/main
+HEAD 4f9c2a7e1b3d5f6a8c0e2b4d6f8a1c3e5b7d9f0a
+branch refs/heads/main
+
+worktree /srv/repo.git/feature
+HEAD 9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b
+branch refs/heads/feature
+
+`
+
+// fixtureRepo returns a Repo whose worktree list is parsed from porcelain
+// output instead of read from git.
+func fixtureRepo(t *testing.T, porcelain string) *Repo {
+	t.Helper()
+	worktrees, err := parseWorktreeList([]byte(porcelain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Repo{worktrees: worktrees, listed: true}
+}
+
+func TestParseWorktreeListBare(t *testing.T) {
+	r := fixtureRepo(t, bareList)
+
+	want := []Worktree{
+		{Path: "/srv/repo.git", Bare: true},
+		{Path: "/srv/repo.git/main", Commit: "4f9c2a7e1b3d5f6a8c0e2b4d6f8a1c3e5b7d9f0a", Branch: "main"},
+		{Path: "/srv/repo.git/feature", Commit: "9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b", Branch: "feature"},
+	}
+	if len(r.worktrees) != len(want) {
+		t.Fatalf("parsed %d worktrees, want %d: %+v", len(r.worktrees), len(want), r.worktrees)
+	}
+	for i := range want {
+		if r.worktrees[i] != want[i] {
+			t.Errorf("worktree %d = %+v, want %+v", i, r.worktrees[i], want[i])
+		}
+	}
+
+	if main, err := r.MainWorktreePath(); err != nil || main != "/srv/repo.git/main" {
+		t.Errorf("MainWorktreePath() = %q, %v; want %q", main, err, "/srv/repo.git/main")
+	}
+}
+
+func TestMainWorktreePathOnlyBare(t *testing.T) {
+	r := fixtureRepo(t, "worktree /srv/repo.git\nbare\n\n")
+
+	if main, err := r.MainWorktreePath(); err != nil || main != "/srv/repo.git" {
+		t.Errorf("MainWorktreePath() = %q, %v; want %q", main, err, "/srv/repo.git")
+	}
+}
+
+func TestFindByBranchPointsIntoList(t *testing.T) {
+	r := fixtureRepo(t, bareList)
+
+	wt, err := r.FindByBranch("feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt.Locked = true
+	wt.LockReason = "on a USB drive"
+
+	again, err := r.FindByBranch("feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != wt {
+		t.Errorf("FindByBranch returned %p, then %p", wt, again)
+	}
+	if got := r.worktrees[2]; !got.Locked || got.LockReason != "on a USB drive" {
+		t.Errorf("list entry = %+v, want the change made through the pointer", got)
+	}
+	if r.worktrees[1].Locked {
+		t.Error("main's entry changed too")
+	}
+}
+
+func TestIsMergedRemoteDefaultBranch(t *testing.T) {
+	newTestRepo(t)
+	git(t, "remote", "add", "origin", "https://example.com/acme/widget.git")
+	git(t, "update-ref", "refs/remotes/origin/main", "HEAD")
+	git(t, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main")
+
+	// Leave main only on the remote
+	git(t, "checkout", "--quiet", "-b", "merged")
+	git(t, "branch", "--quiet", "-D", "main")
+	git(t, "checkout", "--quiet", "-b", "unmerged")
+	git(t, "commit", "--quiet", "--allow-empty", "-m", "unmerged work")
+
+	tests := []struct {
+		branch string
+		want   bool
+	}{
+		{"merged", true},
+		{"unmerged", false},
+	}
+	for _, tt := range tests {
+		if got, err := IsMerged(tt.branch); err != nil || got != tt.want {
+			t.Errorf("IsMerged(%s) = %v, %v; want %v", tt.branch, got, err, tt.want)
+		}
+	}
+}